@@ -15,6 +15,14 @@ type Config struct {
 		Debug bool `toml:"debug"`
 	} `toml:"settings"`
 	Database DatabaseConfig `toml:"database"`
+	Mission  MissionConfig  `toml:"mission"`
+}
+
+type MissionConfig struct {
+	// 加入任务时回放的历史事件数量，0 表示使用默认值，负数表示不回放
+	ReplayEvents int `toml:"replay_events"`
+	// 教员用户名，只有教员可以向运行中的任务注入故障
+	Instructors []string `toml:"instructors"`
 }
 
 type DatabaseConfig struct {
@@ -0,0 +1,215 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/eli-yip/rocket-control/db"
+	"github.com/eli-yip/rocket-control/mission"
+	"github.com/labstack/echo/v4"
+	"github.com/rezakhademix/govalidator/v2"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type (
+	AccidentRequest struct {
+		Name     string              `json:"name"`
+		Desc     string              `json:"desc"`
+		Weight   *float64            `json:"weight"` // 默认为 1
+		Severity db.AccidentSeverity `json:"severity"`
+		Phases   []db.FlightPhase    `json:"phases"`
+		Steps    db.ProgramSteps     `json:"steps"`
+	}
+
+	// AccidentResp 事故的返回结构，Steps 为解析后的步骤
+	AccidentResp struct {
+		ID        uint                `json:"id"`
+		CreatedAt time.Time           `json:"created_at"`
+		UpdatedAt time.Time           `json:"updated_at"`
+		Name      string              `json:"name"`
+		Desc      string              `json:"desc"`
+		Weight    float64             `json:"weight"`
+		Severity  db.AccidentSeverity `json:"severity"`
+		Phases    []db.FlightPhase    `json:"phases"`
+		Steps     db.ProgramSteps     `json:"steps"`
+	}
+)
+
+type AccidentHandler struct{ db db.AccidentIface }
+
+func NewAccidentHandler(db db.AccidentIface) *AccidentHandler { return &AccidentHandler{db: db} }
+
+func newAccidentResp(a *db.Accident) (*AccidentResp, error) {
+	steps, err := a.ProgramSteps()
+	if err != nil {
+		return nil, err
+	}
+	return &AccidentResp{
+		ID:        a.ID,
+		CreatedAt: a.CreatedAt,
+		UpdatedAt: a.UpdatedAt,
+		Name:      a.Name,
+		Desc:      a.Desc,
+		Weight:    a.Weight,
+		Severity:  a.Severity,
+		Phases:    a.Phases,
+		Steps:     steps,
+	}, nil
+}
+
+// bindAccidentRequest 解析并校验请求，返回待保存的事故，校验失败时已经写入响应，返回 nil
+func bindAccidentRequest(c echo.Context, logger *zap.Logger) (*db.Accident, error) {
+	var req AccidentRequest
+	if err := c.Bind(&req); err != nil {
+		logger.Error("failed to bind request", zap.Error(err))
+		return nil, c.JSON(http.StatusBadRequest, WrapResp("failed to bind request"))
+	}
+	weight := 1.0
+	if req.Weight != nil {
+		weight = *req.Weight
+	}
+
+	v := govalidator.New()
+	v.RequiredString(req.Name, "name", "name is required")
+	v.CustomRule(weight > 0, "weight", "weight must be positive")
+	v.CustomRule(req.Severity >= db.AccidentSeverityMinor && req.Severity <= db.AccidentSeverityCritical, "severity", "invalid severity")
+	for _, p := range req.Phases {
+		v.CustomRule(mission.ValidFlightPhase(p), "phases", fmt.Sprintf("invalid flight phase %q", p))
+	}
+	if v.IsFailed() {
+		for k, v := range v.Errors() {
+			logger.Error("validation failed", zap.String("field", k), zap.String("error", v))
+		}
+		return nil, c.JSON(http.StatusBadRequest, WrapRespWithData("validation failed", v.Errors()))
+	}
+	if err := mission.ValidateProgramSteps(req.Steps); err != nil {
+		logger.Error("invalid accident steps", zap.Error(err))
+		return nil, c.JSON(http.StatusBadRequest, WrapResp(err.Error()))
+	}
+
+	a := &db.Accident{
+		Name:     req.Name,
+		Desc:     req.Desc,
+		Weight:   weight,
+		Severity: req.Severity,
+		Phases:   req.Phases,
+	}
+	if err := a.SetProgramSteps(req.Steps); err != nil {
+		logger.Error("failed to set accident steps", zap.Error(err))
+		return nil, c.JSON(http.StatusBadRequest, WrapResp("invalid accident steps"))
+	}
+	return a, nil
+}
+
+func (h *AccidentHandler) AddAccident(c echo.Context) (err error) {
+	logger := ExtractLogger(c)
+
+	a, err := bindAccidentRequest(c, logger)
+	if a == nil {
+		return err
+	}
+	if err = h.db.AddAccident(a); err != nil {
+		logger.Error("failed to add accident", zap.Error(err))
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return c.JSON(http.StatusConflict, WrapResp("accident name already exists"))
+		}
+		return c.JSON(http.StatusInternalServerError, WrapResp("failed to add accident"))
+	}
+	resp, err := newAccidentResp(a)
+	if err != nil {
+		logger.Error("failed to parse accident steps", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, WrapResp("failed to parse accident steps"))
+	}
+	return c.JSON(http.StatusOK, WrapRespWithData("success", resp))
+}
+
+func (h *AccidentHandler) GetAccident(c echo.Context) (err error) {
+	logger := ExtractLogger(c)
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		logger.Error("invalid accident id", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, WrapResp("invalid accident id"))
+	}
+	a, err := h.db.GetAccident(uint(id))
+	if err != nil {
+		logger.Error("failed to get accident", zap.Error(err))
+		return c.JSON(http.StatusNotFound, WrapResp("accident not found"))
+	}
+	resp, err := newAccidentResp(a)
+	if err != nil {
+		logger.Error("failed to parse accident steps", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, WrapResp("failed to parse accident steps"))
+	}
+	return c.JSON(http.StatusOK, WrapRespWithData("success", resp))
+}
+
+func (h *AccidentHandler) GetAccidentList(c echo.Context) (err error) {
+	logger := ExtractLogger(c)
+	list, err := h.db.GetAccidentList()
+	if err != nil {
+		logger.Error("failed to get accident list", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, WrapResp("failed to get accident list"))
+	}
+	resp := make([]*AccidentResp, 0, len(list))
+	for _, a := range list {
+		r, err := newAccidentResp(a)
+		if err != nil {
+			logger.Error("failed to parse accident steps", zap.Uint("id", a.ID), zap.Error(err))
+			return c.JSON(http.StatusInternalServerError, WrapResp("failed to parse accident steps"))
+		}
+		resp = append(resp, r)
+	}
+	return c.JSON(http.StatusOK, WrapRespWithData("success", resp))
+}
+
+func (h *AccidentHandler) UpdateAccident(c echo.Context) (err error) {
+	logger := ExtractLogger(c)
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		logger.Error("invalid accident id", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, WrapResp("invalid accident id"))
+	}
+	if _, err = h.db.GetAccident(uint(id)); err != nil {
+		logger.Error("failed to get accident", zap.Error(err))
+		return c.JSON(http.StatusNotFound, WrapResp("accident not found"))
+	}
+
+	a, err := bindAccidentRequest(c, logger)
+	if a == nil {
+		return err
+	}
+	a.ID = uint(id)
+	if err = h.db.UpdateAccident(a); err != nil {
+		logger.Error("failed to update accident", zap.Error(err))
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return c.JSON(http.StatusConflict, WrapResp("accident name already exists"))
+		}
+		return c.JSON(http.StatusInternalServerError, WrapResp("failed to update accident"))
+	}
+	return c.JSON(http.StatusOK, WrapResp("success"))
+}
+
+func (h *AccidentHandler) DeleteAccident(c echo.Context) (err error) {
+	logger := ExtractLogger(c)
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		logger.Error("invalid accident id", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, WrapResp("invalid accident id"))
+	}
+	if _, err = h.db.GetAccident(uint(id)); err != nil {
+		logger.Error("failed to get accident", zap.Error(err))
+		return c.JSON(http.StatusNotFound, WrapResp("accident not found"))
+	}
+	if err = h.db.DeleteAccident(uint(id)); err != nil {
+		logger.Error("failed to delete accident", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, WrapResp("failed to delete accident"))
+	}
+	return c.JSON(http.StatusOK, WrapResp("success"))
+}
@@ -0,0 +1,49 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestAddAccidentValidation(t *testing.T) {
+	h := NewAccidentHandler(newFakeDB())
+	steps := `"steps":[{"event_type":"thrust","value":"10"}]`
+
+	for _, tc := range []struct {
+		name, body string
+		want       int
+	}{
+		{"valid", `{"name":"engine fire","severity":2,"weight":3,"phases":["ascent","orbit"],` + steps + `}`, http.StatusOK},
+		{"duplicate name", `{"name":"engine fire",` + steps + `}`, http.StatusConflict},
+		{"missing name", `{` + steps + `}`, http.StatusBadRequest},
+		{"zero weight", `{"name":"leak","weight":0,` + steps + `}`, http.StatusBadRequest},
+		{"negative weight", `{"name":"leak","weight":-1,` + steps + `}`, http.StatusBadRequest},
+		{"invalid severity", `{"name":"leak","severity":3,` + steps + `}`, http.StatusBadRequest},
+		{"invalid phase", `{"name":"leak","phases":["warp"],` + steps + `}`, http.StatusBadRequest},
+		{"invalid steps", `{"name":"leak","steps":[{"event_type":"thrust","value":"x"}]}`, http.StatusBadRequest},
+	} {
+		c, rec := newTestContext(http.MethodPost, "/api/v1/accident", tc.body)
+		if err := h.AddAccident(c); err != nil {
+			t.Fatalf("%s: AddAccident returned error: %v", tc.name, err)
+		}
+		if rec.Code != tc.want {
+			t.Errorf("%s: status %d, want %d: %s", tc.name, rec.Code, tc.want, rec.Body)
+		}
+	}
+}
+
+func TestAddAccidentDefaultWeight(t *testing.T) {
+	h := NewAccidentHandler(newFakeDB())
+	c, rec := newTestContext(http.MethodPost, "/api/v1/accident", `{"name":"leak","steps":[{"event_type":"thrust","value":"10"}]}`)
+	if err := h.AddAccident(c); err != nil {
+		t.Fatalf("AddAccident returned error: %v", err)
+	}
+	var resp ApiResp[AccidentResp]
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Data.Weight != 1 || len(resp.Data.Steps) != 1 {
+		t.Errorf("accident weight %v, %d steps, want weight 1 and 1 step", resp.Data.Weight, len(resp.Data.Steps))
+	}
+}
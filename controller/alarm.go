@@ -0,0 +1,57 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/eli-yip/rocket-control/db"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+type AlarmHandler struct{ db db.AlarmIface }
+
+func NewAlarmHandler(db db.AlarmIface) *AlarmHandler { return &AlarmHandler{db: db} }
+
+func (h *AlarmHandler) GetAlarm(c echo.Context) (err error) {
+	logger := ExtractLogger(c)
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		logger.Error("invalid alarm id", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, WrapResp("invalid alarm id"))
+	}
+	alarm, err := h.db.GetAlarm(uint(id))
+	if err != nil {
+		logger.Error("failed to get alarm", zap.Error(err))
+		return c.JSON(http.StatusNotFound, WrapResp("alarm not found"))
+	}
+	return c.JSON(http.StatusOK, WrapRespWithData("success", alarm))
+}
+
+// GetAlarmList 返回任务的告警列表，active=true 时只返回未清除的告警
+func (h *AlarmHandler) GetAlarmList(c echo.Context) (err error) {
+	logger := ExtractLogger(c)
+	missionIDStr := c.QueryParam("mission_id")
+	if missionIDStr == "" {
+		return c.JSON(http.StatusBadRequest, WrapResp("mission_id is required"))
+	}
+	missionID, err := strconv.ParseUint(missionIDStr, 10, 64)
+	if err != nil {
+		logger.Error("invalid mission_id", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, WrapResp("invalid mission_id"))
+	}
+	activeOnly := false
+	if activeStr := c.QueryParam("active"); activeStr != "" {
+		if activeOnly, err = strconv.ParseBool(activeStr); err != nil {
+			logger.Error("invalid active", zap.Error(err))
+			return c.JSON(http.StatusBadRequest, WrapResp("invalid active"))
+		}
+	}
+	list, err := h.db.GetAlarmList(uint(missionID), activeOnly)
+	if err != nil {
+		logger.Error("failed to get alarm list", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, WrapResp("failed to get alarm list"))
+	}
+	return c.JSON(http.StatusOK, WrapRespWithData("success", list))
+}
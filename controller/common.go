@@ -9,6 +9,12 @@ func ExtractLogger(c echo.Context) *zap.Logger {
 	return c.Get("logger").(*zap.Logger)
 }
 
+// IsInstructor 返回当前用户是否为教员，由 InjectUser 设置
+func IsInstructor(c echo.Context) bool {
+	instructor, _ := c.Get("instructor").(bool)
+	return instructor
+}
+
 // ApiResp represents the structure of the API response.
 type ApiResp[T any] struct {
 	Message string `json:"message,omitempty"`
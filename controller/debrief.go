@@ -0,0 +1,138 @@
+package controller
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eli-yip/rocket-control/db"
+	"github.com/eli-yip/rocket-control/mission"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// 资源曲线 SVG 的绘图区域大小
+const (
+	curveWidth  = 600
+	curveHeight = 120
+)
+
+var (
+	missionStatusNames    = []string{"pending", "in progress", "completed", "failed", "cancelled", "paused"}
+	eventStatusNames      = []string{"pending", "in progress", "completed", "failed", "cancelled"}
+	alarmStatusNames      = []string{"active", "acknowledged", "cleared"}
+	alarmLevelNames       = []string{"warning", "critical"}
+	diagnosticStatusNames = []string{"pending", "running", "completed", "failed"}
+)
+
+//go:embed debrief.html
+var debriefHTML string
+
+// debriefTemplate 渲染独立的复盘页面，样式和曲线都内联在页面中
+var debriefTemplate = template.Must(template.New("debrief").Funcs(template.FuncMap{
+	"missionStatus":    func(s db.MissionStatus) string { return nameOf(missionStatusNames, int(s)) },
+	"eventStatus":      func(s db.EventStatus) string { return nameOf(eventStatusNames, int(s)) },
+	"alarmStatus":      func(s db.AlarmStatus) string { return nameOf(alarmStatusNames, int(s)) },
+	"alarmLevel":       func(l db.AlarmLevel) string { return nameOf(alarmLevelNames, int(l)) },
+	"diagnosticStatus": func(s db.DiagnosticStatus) string { return nameOf(diagnosticStatusNames, int(s)) },
+	"num":              func(v float64) string { return strconv.FormatFloat(v, 'f', 1, 64) },
+	"offset":           formatOffset,
+	"clock":            formatClock,
+	"polyline":         polyline,
+	"jsonb":            func(d *db.Diagnostic) string { return string(d.Result.Bytes) },
+}).Parse(debriefHTML))
+
+func nameOf(names []string, i int) string {
+	if i < 0 || i >= len(names) {
+		return strconv.Itoa(i)
+	}
+	return names[i]
+}
+
+// formatOffset 将相对任务开始的秒数格式化为 T+mm:ss
+func formatOffset(seconds float64) string {
+	sign := "+"
+	if seconds < 0 {
+		sign, seconds = "-", -seconds
+	}
+	s := int(seconds)
+	return fmt.Sprintf("T%s%02d:%02d", sign, s/60, s%60)
+}
+
+func formatClock(t any) string {
+	switch v := t.(type) {
+	case time.Time:
+		if !v.IsZero() {
+			return v.Local().Format(time.DateTime)
+		}
+	case *time.Time:
+		if v != nil && !v.IsZero() {
+			return v.Local().Format(time.DateTime)
+		}
+	}
+	return "-"
+}
+
+// polyline 将资源曲线映射到 curveWidth x curveHeight 的绘图区域，返回 SVG polyline 的 points
+func polyline(c mission.ResourceCurve) string {
+	if len(c.Points) == 0 {
+		return ""
+	}
+	t0, t1 := c.Points[0].T, c.Points[len(c.Points)-1].T
+	var b strings.Builder
+	for i, p := range c.Points {
+		x, y := 0.0, curveHeight/2.0
+		if t1 > t0 {
+			x = (p.T - t0) / (t1 - t0) * curveWidth
+		}
+		if c.Max > c.Min {
+			y = curveHeight - (p.V-c.Min)/(c.Max-c.Min)*curveHeight
+		}
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		fmt.Fprintf(&b, "%.1f,%.1f", x, y)
+	}
+	return b.String()
+}
+
+// GetDebrief 生成任务的复盘报告，format=html 时返回独立的 HTML 页面，否则返回 JSON。
+// 教员注入的事件只出现在教员的报告中。
+func (h *MissionHandler) GetDebrief(c echo.Context) (err error) {
+	logger := ExtractLogger(c)
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		logger.Error("invalid mission id", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, WrapResp("invalid mission id"))
+	}
+	format := c.QueryParam("format")
+	if format != "" && format != "json" && format != "html" {
+		return c.JSON(http.StatusBadRequest, WrapResp("format must be json or html"))
+	}
+
+	debrief, err := mission.BuildDebrief(h.db, uint(id), IsInstructor(c))
+	if err != nil {
+		logger.Error("failed to build debrief", zap.Uint64("mission_id", id), zap.Error(err))
+		if errors.Is(err, mission.ErrMissionNotFound) {
+			return c.JSON(http.StatusNotFound, WrapResp("mission not found"))
+		}
+		return c.JSON(http.StatusInternalServerError, WrapResp("failed to build debrief"))
+	}
+
+	if format != "html" {
+		return c.JSON(http.StatusOK, WrapRespWithData("success", debrief))
+	}
+	var buf bytes.Buffer
+	if err = debriefTemplate.Execute(&buf, debrief); err != nil {
+		logger.Error("failed to render debrief", zap.Uint64("mission_id", id), zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, WrapResp("failed to render debrief"))
+	}
+	return c.HTMLBlob(http.StatusOK, buf.Bytes())
+}
@@ -0,0 +1,178 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eli-yip/rocket-control/db"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultEventPageSize = 100
+	maxEventPageSize     = 1000
+)
+
+type EventHandler struct{ db db.EventIface }
+
+func NewEventHandler(db db.EventIface) *EventHandler { return &EventHandler{db: db} }
+
+// EventPage 是一页事件历史，NextCursor 为 0 表示没有更多事件
+type EventPage struct {
+	Events     []*db.Event `json:"events"`
+	NextCursor uint        `json:"next_cursor,omitempty"`
+}
+
+// GetEventList 返回任务的事件历史，支持以下查询参数：
+// type（可重复或以逗号分隔）、status、created_by、since、until（RFC3339）、
+// part_of（父事件 ID，0 表示只返回顶层事件）、cursor、limit。教员注入的事件只返回给教员。
+func (h *EventHandler) GetEventList(c echo.Context) (err error) {
+	logger := ExtractLogger(c)
+	missionID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		logger.Error("invalid mission id", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, WrapResp("invalid mission id"))
+	}
+
+	// 教员注入的事件只有教员可以查询
+	filter := db.EventFilter{CreatedBy: c.QueryParam("created_by"), Limit: defaultEventPageSize, IncludeInjected: IsInstructor(c)}
+	for _, t := range c.QueryParams()["type"] {
+		for _, typ := range strings.Split(t, ",") {
+			if typ = strings.TrimSpace(typ); typ != "" {
+				filter.Types = append(filter.Types, db.EventType(typ))
+			}
+		}
+	}
+	if v := c.QueryParam("status"); v != "" {
+		status, err := strconv.Atoi(v)
+		if err != nil {
+			logger.Error("invalid status", zap.Error(err))
+			return c.JSON(http.StatusBadRequest, WrapResp("invalid status"))
+		}
+		eventStatus := db.EventStatus(status)
+		filter.Status = &eventStatus
+	}
+	if v := c.QueryParam("since"); v != "" {
+		if filter.Since, err = time.Parse(time.RFC3339, v); err != nil {
+			logger.Error("invalid since", zap.Error(err))
+			return c.JSON(http.StatusBadRequest, WrapResp("invalid since"))
+		}
+	}
+	if v := c.QueryParam("until"); v != "" {
+		if filter.Until, err = time.Parse(time.RFC3339, v); err != nil {
+			logger.Error("invalid until", zap.Error(err))
+			return c.JSON(http.StatusBadRequest, WrapResp("invalid until"))
+		}
+	}
+	if v := c.QueryParam("part_of"); v != "" {
+		partOf, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			logger.Error("invalid part_of", zap.Error(err))
+			return c.JSON(http.StatusBadRequest, WrapResp("invalid part_of"))
+		}
+		parentID := uint(partOf)
+		filter.PartOf = &parentID
+	}
+	if v := c.QueryParam("cursor"); v != "" {
+		cursor, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			logger.Error("invalid cursor", zap.Error(err))
+			return c.JSON(http.StatusBadRequest, WrapResp("invalid cursor"))
+		}
+		filter.Cursor = uint(cursor)
+	}
+	if v := c.QueryParam("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil || filter.Limit <= 0 || filter.Limit > maxEventPageSize {
+			logger.Error("invalid limit", zap.String("limit", v))
+			return c.JSON(http.StatusBadRequest, WrapResp("limit must be between 1 and "+strconv.Itoa(maxEventPageSize)))
+		}
+	}
+
+	// 多取一条判断是否还有下一页
+	pageSize := filter.Limit
+	filter.Limit++
+	events, err := h.db.GetEventList(uint(missionID), filter)
+	if err != nil {
+		logger.Error("failed to get event list", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, WrapResp("failed to get event list"))
+	}
+
+	page := EventPage{Events: events}
+	if len(events) > pageSize {
+		page.Events = events[:pageSize]
+		page.NextCursor = page.Events[pageSize-1].ID
+	}
+	return c.JSON(http.StatusOK, WrapRespWithData("success", page))
+}
+
+// maxEventTreeDepth 限制事件树的深度，防止错误的 PartOf 形成环
+const maxEventTreeDepth = 32
+
+// EventNode 是事件树中的一个节点
+type EventNode struct {
+	*db.Event
+	Duration int64        `json:"duration"` // 执行时长（毫秒），未结束时计算到当前时间
+	Children []*EventNode `json:"children"`
+}
+
+// GetEventTree 返回一个事件及其全部子事件组成的树
+func (h *EventHandler) GetEventTree(c echo.Context) (err error) {
+	logger := ExtractLogger(c)
+	missionID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		logger.Error("invalid mission id", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, WrapResp("invalid mission id"))
+	}
+	eventID, err := strconv.ParseUint(c.Param("event_id"), 10, 64)
+	if err != nil {
+		logger.Error("invalid event id", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, WrapResp("invalid event id"))
+	}
+
+	event, err := h.db.GetEvent(uint(eventID))
+	if err != nil || event.MissionID != uint(missionID) || (event.Injected && !IsInstructor(c)) {
+		logger.Error("failed to get event", zap.Error(err))
+		return c.JSON(http.StatusNotFound, WrapResp("event not found"))
+	}
+
+	root := newEventNode(event)
+	level := []*EventNode{root}
+	for depth := 0; len(level) > 0 && depth < maxEventTreeDepth; depth++ {
+		nodes := make(map[uint]*EventNode, len(level))
+		parentIDs := make([]uint, 0, len(level))
+		for _, n := range level {
+			nodes[n.ID] = n
+			parentIDs = append(parentIDs, n.ID)
+		}
+		children, err := h.db.GetSubEvents(parentIDs)
+		if err != nil {
+			logger.Error("failed to get sub events", zap.Error(err))
+			return c.JSON(http.StatusInternalServerError, WrapResp("failed to get sub events"))
+		}
+		level = level[:0:0]
+		for _, child := range children {
+			n := newEventNode(child)
+			nodes[child.PartOf].Children = append(nodes[child.PartOf].Children, n)
+			level = append(level, n)
+		}
+	}
+
+	return c.JSON(http.StatusOK, WrapRespWithData("success", root))
+}
+
+func newEventNode(e *db.Event) *EventNode {
+	n := &EventNode{Event: e, Children: []*EventNode{}}
+	start := e.CreatedAt
+	if e.StartedAt != nil {
+		start = *e.StartedAt
+	}
+	end := time.Now()
+	if e.FinishedAt != nil {
+		end = *e.FinishedAt
+	}
+	n.Duration = end.Sub(start).Milliseconds()
+	return n
+}
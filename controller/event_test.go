@@ -0,0 +1,133 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func getEventPage(t *testing.T, h *EventHandler, query string) EventPage {
+	t.Helper()
+	c, rec := newTestContext(http.MethodGet, "/api/v1/mission/1/event?"+query, "", "id", "1")
+	if err := h.GetEventList(c); err != nil {
+		t.Fatalf("GetEventList returned error: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GetEventList?%s status %d: %s", query, rec.Code, rec.Body)
+	}
+	var resp ApiResp[EventPage]
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return resp.Data
+}
+
+func TestEventListCursorPagination(t *testing.T) {
+	fdb := &fakeDB{}
+	for range 5 {
+		fdb.addEvent(1, 0, false)
+	}
+	fdb.addEvent(2, 0, false)
+	h := NewEventHandler(fdb)
+
+	// 每页的 NextCursor 是该页最后一个事件的 ID，其他任务的事件不会出现
+	var ids, cursors []uint
+	for query := "limit=2"; ; {
+		page := getEventPage(t, h, query)
+		for _, e := range page.Events {
+			ids = append(ids, e.ID)
+		}
+		if page.NextCursor == 0 {
+			break
+		}
+		cursors = append(cursors, page.NextCursor)
+		query = "limit=2&cursor=" + strconv.FormatUint(uint64(page.NextCursor), 10)
+	}
+	if len(ids) != 5 || ids[0] != 1 || ids[4] != 5 {
+		t.Errorf("paged events %v, want [1 2 3 4 5]", ids)
+	}
+	if len(cursors) != 2 || cursors[0] != 2 || cursors[1] != 4 {
+		t.Errorf("cursors %v, want [2 4]", cursors)
+	}
+
+	// 最后一页正好填满时不返回 NextCursor
+	if page := getEventPage(t, h, "limit=5"); len(page.Events) != 5 || page.NextCursor != 0 {
+		t.Errorf("exact page: %d events, next cursor %d, want 5 events and no cursor", len(page.Events), page.NextCursor)
+	}
+	if page := getEventPage(t, h, "limit=4"); len(page.Events) != 4 || page.NextCursor != 4 {
+		t.Errorf("short page: %d events, next cursor %d, want 4 events and cursor 4", len(page.Events), page.NextCursor)
+	}
+}
+
+func TestEventListInvalidQuery(t *testing.T) {
+	h := NewEventHandler(&fakeDB{})
+	for _, query := range []string{"limit=0", "limit=1001", "cursor=x", "status=x", "since=yesterday", "part_of=-1"} {
+		c, rec := newTestContext(http.MethodGet, "/api/v1/mission/1/event?"+query, "", "id", "1")
+		if err := h.GetEventList(c); err != nil {
+			t.Fatalf("GetEventList returned error: %v", err)
+		}
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("GetEventList?%s status %d, want 400", query, rec.Code)
+		}
+	}
+}
+
+func TestInjectedEventsOnlyForInstructors(t *testing.T) {
+	fdb := &fakeDB{}
+	fdb.addEvent(1, 0, false)
+	injected := fdb.addEvent(1, 0, true)
+	fdb.addEvent(1, injected.ID, true)
+	h := NewEventHandler(fdb)
+
+	for _, instructor := range []bool{false, true} {
+		c, rec := newTestContext(http.MethodGet, "/api/v1/mission/1/event", "", "id", "1")
+		c.Set("instructor", instructor)
+		if err := h.GetEventList(c); err != nil {
+			t.Fatalf("GetEventList returned error: %v", err)
+		}
+		var resp ApiResp[EventPage]
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		want := 1
+		if instructor {
+			want = 3
+		}
+		if len(resp.Data.Events) != want {
+			t.Errorf("instructor %v: got %d events, want %d", instructor, len(resp.Data.Events), want)
+		}
+
+		c, rec = newTestContext(http.MethodGet, "/api/v1/mission/1/event/2/tree", "", "id", "1", "event_id", "2")
+		c.Set("instructor", instructor)
+		if err := h.GetEventTree(c); err != nil {
+			t.Fatalf("GetEventTree returned error: %v", err)
+		}
+		wantCode := http.StatusNotFound
+		if instructor {
+			wantCode = http.StatusOK
+		}
+		if rec.Code != wantCode {
+			t.Errorf("instructor %v: event tree status %d, want %d", instructor, rec.Code, wantCode)
+			continue
+		}
+		if instructor {
+			var tree ApiResp[EventNode]
+			if err := json.Unmarshal(rec.Body.Bytes(), &tree); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if len(tree.Data.Children) != 1 {
+				t.Errorf("event tree has %d children, want 1", len(tree.Data.Children))
+			}
+		}
+	}
+
+	// 没有设置 instructor 时按非教员处理
+	c, rec := newTestContext(http.MethodGet, "/api/v1/mission/1/event/3/tree", "", "id", "1", "event_id", "3")
+	if err := h.GetEventTree(c); err != nil {
+		t.Fatalf("GetEventTree returned error: %v", err)
+	}
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("injected sub event tree status %d, want 404", rec.Code)
+	}
+}
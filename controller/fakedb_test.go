@@ -0,0 +1,314 @@
+package controller
+
+import (
+	"io"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eli-yip/rocket-control/db"
+	"github.com/eli-yip/rocket-control/log"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestMain(m *testing.M) {
+	log.DefaultLogger = zap.NewNop()
+	os.Exit(m.Run())
+}
+
+// fakeDB 在内存中实现处理器需要的数据库操作，未实现的方法调用时会 panic
+type fakeDB struct {
+	db.Iface
+	mu        sync.Mutex
+	missions  map[uint]*db.Mission
+	programs  map[uint]*db.CustomProgram
+	presets   map[uint]*db.SystemPreset
+	accidents []*db.Accident
+	templates map[uint]*db.MissionTemplate
+	events    []*db.Event // 第 i 个事件的 ID 为 i+1
+}
+
+func newFakeDB(missions ...*db.Mission) *fakeDB {
+	f := &fakeDB{
+		missions:  make(map[uint]*db.Mission),
+		programs:  make(map[uint]*db.CustomProgram),
+		presets:   make(map[uint]*db.SystemPreset),
+		templates: make(map[uint]*db.MissionTemplate),
+	}
+	for _, m := range missions {
+		f.missions[m.ID] = m
+	}
+	return f
+}
+
+func (f *fakeDB) AddMission(name, user string, duration int, opts ...db.MissionOptFunc) (*db.Mission, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, m := range f.missions {
+		if m.Name == name {
+			return nil, gorm.ErrDuplicatedKey
+		}
+	}
+	m := &db.Mission{Name: name, CreatedBy: user, Duration: duration, Status: db.MissionStatusPending, SuccessRate: db.DefaultSuccessRate}
+	for _, opt := range opts {
+		opt(m)
+	}
+	m.ID = uint(len(f.missions) + 1)
+	f.missions[m.ID] = m
+	return m, nil
+}
+
+func (f *fakeDB) GetMission(id uint) (*db.Mission, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	m, ok := f.missions[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	info := *m
+	return &info, nil
+}
+
+func (f *fakeDB) mission(id uint) db.Mission {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return *f.missions[id]
+}
+
+func (f *fakeDB) StartMission(id uint, at time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.missions[id].Status, f.missions[id].StartTime = db.MissionStatusInProgress, at
+	return nil
+}
+
+func (f *fakeDB) EndMission(id uint, status db.MissionStatus, result *db.MissionResult, at time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	m := f.missions[id]
+	m.Status, m.Result, m.EndTime = status, result, at
+	return nil
+}
+
+// 任务第一次加载时没有 SystemState，使用初始状态创建，之后的状态变化不需要保存
+func (f *fakeDB) GetSystemState(uint) (*db.SystemState, error) { return nil, gorm.ErrRecordNotFound }
+
+func (f *fakeDB) AddSystemState(missionID uint, setting db.RocketSetting, status db.RocketStatus) (*db.SystemState, error) {
+	return &db.SystemState{MissionID: missionID, RocketSetting: setting, RocketStatus: status}, nil
+}
+
+func (f *fakeDB) UpdateSystemStatus(uint, db.RocketStatus) error { return nil }
+
+func (f *fakeDB) GetAlarmList(uint, bool) ([]*db.Alarm, error) { return nil, nil }
+
+func (f *fakeDB) AddEvent(missionID uint, eventType db.EventType, value string, createdBy string) (*db.Event, error) {
+	return f.appendEvent(&db.Event{MissionID: missionID, Type: eventType, Value: value, CreatedBy: createdBy}), nil
+}
+
+func (f *fakeDB) UpdateEventStatus(uint, db.EventStatus) error { return nil }
+
+func (f *fakeDB) addEvent(missionID, partOf uint, injected bool) *db.Event {
+	return f.appendEvent(&db.Event{MissionID: missionID, PartOf: partOf, Injected: injected, Type: db.EventTypeMissionWarning})
+}
+
+func (f *fakeDB) appendEvent(e *db.Event) *db.Event {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	e.ID = uint(len(f.events) + 1)
+	f.events = append(f.events, e)
+	return e
+}
+
+func (f *fakeDB) GetEventList(missionID uint, filter db.EventFilter) ([]*db.Event, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var list []*db.Event
+	for _, e := range f.events {
+		if e.MissionID != missionID || e.ID <= filter.Cursor || (e.Injected && !filter.IncludeInjected) {
+			continue
+		}
+		if len(filter.Types) > 0 && !slices.Contains(filter.Types, e.Type) {
+			continue
+		}
+		if filter.PartOf != nil && e.PartOf != *filter.PartOf {
+			continue
+		}
+		if filter.Limit >= 0 && len(list) == filter.Limit {
+			break
+		}
+		list = append(list, e)
+	}
+	return list, nil
+}
+
+func (f *fakeDB) GetEvent(id uint) (*db.Event, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if id == 0 || int(id) > len(f.events) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return f.events[id-1], nil
+}
+
+func (f *fakeDB) GetSubEvents(parentIDs []uint) ([]*db.Event, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var list []*db.Event
+	for _, e := range f.events {
+		for _, id := range parentIDs {
+			if e.PartOf == id {
+				list = append(list, e)
+			}
+		}
+	}
+	return list, nil
+}
+
+func (f *fakeDB) AddCustomProgram(name, desc string, steps db.ProgramSteps) (*db.CustomProgram, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, p := range f.programs {
+		if p.Name == name {
+			return nil, gorm.ErrDuplicatedKey
+		}
+	}
+	p := &db.CustomProgram{Name: name, Desc: desc}
+	if err := p.Steps.Set(steps); err != nil {
+		return nil, err
+	}
+	p.ID = uint(len(f.programs) + 1)
+	f.programs[p.ID] = p
+	return p, nil
+}
+
+func (f *fakeDB) GetCustomProgram(id uint) (*db.CustomProgram, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	p, ok := f.programs[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return p, nil
+}
+
+func (f *fakeDB) UpdateCustomProgram(id uint, name, desc string, steps db.ProgramSteps) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	p := f.programs[id]
+	p.Name, p.Desc = name, desc
+	return p.Steps.Set(steps)
+}
+
+func (f *fakeDB) DeleteCustomProgram(id uint) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.programs, id)
+	return nil
+}
+
+func (f *fakeDB) AddPreset(name, desc string, setting db.RocketSetting) (*db.SystemPreset, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, p := range f.presets {
+		if p.Name == name {
+			return nil, gorm.ErrDuplicatedKey
+		}
+	}
+	p := &db.SystemPreset{Name: name, Desc: desc, RocketSetting: setting}
+	p.ID = uint(len(f.presets) + 1)
+	f.presets[p.ID] = p
+	return p, nil
+}
+
+func (f *fakeDB) GetPreset(id uint) (*db.SystemPreset, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	p, ok := f.presets[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return p, nil
+}
+
+func (f *fakeDB) UpdatePreset(id uint, name, desc string, setting db.RocketSetting) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, p := range f.presets {
+		if p.ID != id && p.Name == name {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+	p := f.presets[id]
+	p.Name, p.Desc, p.RocketSetting = name, desc, setting
+	return nil
+}
+
+func (f *fakeDB) AddAccident(a *db.Accident) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, e := range f.accidents {
+		if e.Name == a.Name {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+	a.ID = uint(len(f.accidents) + 1)
+	f.accidents = append(f.accidents, a)
+	return nil
+}
+
+func (f *fakeDB) GetAccidentByName(name string) (*db.Accident, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, a := range f.accidents {
+		if a.Name == name {
+			return a, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f *fakeDB) AddMissionTemplate(t *db.MissionTemplate) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t.ID = uint(len(f.templates) + 1)
+	f.templates[t.ID] = t
+	return nil
+}
+
+func (f *fakeDB) GetMissionTemplate(id uint) (*db.MissionTemplate, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t, ok := f.templates[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return t, nil
+}
+
+// newTestContext 创建一个请求的 echo.Context，params 依次为路径参数的名称和值
+func newTestContext(method, target, body string, params ...string) (echo.Context, *httptest.ResponseRecorder) {
+	var r io.Reader
+	if body != "" {
+		r = strings.NewReader(body)
+	}
+	req := httptest.NewRequest(method, target, r)
+	if body != "" {
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	}
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+	c.Set("logger", zap.NewNop())
+	var names, values []string
+	for i := 0; i+1 < len(params); i += 2 {
+		names, values = append(names, params[i]), append(values, params[i+1])
+	}
+	c.SetParamNames(names...)
+	c.SetParamValues(values...)
+	return c, rec
+}
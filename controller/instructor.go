@@ -0,0 +1,174 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/eli-yip/rocket-control/db"
+	"github.com/eli-yip/rocket-control/mission"
+	"github.com/labstack/echo/v4"
+	"github.com/rezakhademix/govalidator/v2"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type (
+	// InjectRequest 注入故障的请求，Accident 和 EventType 二选一
+	InjectRequest struct {
+		Accident  string       `json:"accident"`   // 事故目录中的事故名称
+		EventType db.EventType `json:"event_type"` // 状态变化事件，例如 hull_change
+		Value     string       `json:"value"`
+		Reason    string       `json:"reason"` // 记录在审计记录中
+	}
+)
+
+type InstructorHandler struct {
+	db             db.Iface
+	missionService *mission.MissionService
+}
+
+func NewInstructorHandler(db db.Iface, missionService *mission.MissionService) *InstructorHandler {
+	return &InstructorHandler{db: db, missionService: missionService}
+}
+
+// Inject 向运行中的任务注入事故或状态变化，注入由 system 发起，无论成功与否都会写入审计记录
+func (h *InstructorHandler) Inject(c echo.Context) (err error) {
+	logger := ExtractLogger(c)
+	user := c.Get("username").(string)
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		logger.Error("invalid mission id", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, WrapResp("invalid mission id"))
+	}
+
+	var req InjectRequest
+	if err = c.Bind(&req); err != nil {
+		logger.Error("failed to bind request", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, WrapResp("failed to bind request"))
+	}
+
+	code, resp := h.inject(logger, user, uint(id), req)
+	return c.JSON(code, resp)
+}
+
+// InjectWs 是 Inject 的 WebSocket 版本：教员连接后可以连续发送 InjectRequest，
+// 每个请求返回一条和 REST 接口相同的响应。连接不会加入任务，教员不会出现在成员列表中。
+func (h *InstructorHandler) InjectWs(c echo.Context) (err error) {
+	logger := ExtractLogger(c)
+	user := c.Get("username").(string)
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		logger.Error("invalid mission id", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, WrapResp("invalid mission id"))
+	}
+
+	opts := &websocket.AcceptOptions{OriginPatterns: []string{"*"}}
+	ws, err := websocket.Accept(c.Response(), c.Request(), opts)
+	if err != nil {
+		logger.Error("failed to accept websocket connection", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, WrapResp("failed to accept websocket connection"))
+	}
+	defer ws.Close(websocket.StatusNormalClosure, "")
+
+	ctx := c.Request().Context()
+	for {
+		var req InjectRequest
+		if err := wsjson.Read(ctx, ws, &req); err != nil {
+			if websocket.CloseStatus(err) == -1 {
+				logger.Error("failed to read message from websocket", zap.Error(err))
+			}
+			return nil
+		}
+
+		_, resp := h.inject(logger, user, uint(id), req)
+		writeCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		err = wsjson.Write(writeCtx, ws, resp)
+		cancel()
+		if err != nil {
+			logger.Error("failed to write message to websocket", zap.Error(err))
+			return nil
+		}
+	}
+}
+
+// inject 执行一次注入并写入审计记录，返回 HTTP 状态码和响应
+func (h *InstructorHandler) inject(logger *zap.Logger, user string, id uint, req InjectRequest) (int, any) {
+	v := govalidator.New()
+	v.CustomRule((req.Accident == "") != (req.EventType == ""), "accident", "exactly one of accident and event_type is required")
+	if v.IsFailed() {
+		for k, v := range v.Errors() {
+			logger.Error("validation failed", zap.String("field", k), zap.String("error", v))
+		}
+		return http.StatusBadRequest, WrapRespWithData("validation failed", v.Errors())
+	}
+
+	audit := &db.InjectionAudit{
+		MissionID:  id,
+		Instructor: user,
+		Value:      req.Value,
+		Reason:     req.Reason,
+	}
+	var injectErr error
+	if req.Accident != "" {
+		audit.Kind, audit.Target = db.InjectionKindAccident, req.Accident
+		a, err := h.db.GetAccidentByName(req.Accident)
+		if err != nil {
+			logger.Error("failed to get accident", zap.String("accident", req.Accident), zap.Error(err))
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return http.StatusNotFound, WrapResp("accident not found")
+			}
+			return http.StatusInternalServerError, WrapResp("failed to get accident")
+		}
+		audit.EventID, injectErr = h.missionService.InjectAccident(id, a)
+	} else {
+		audit.Kind, audit.Target = db.InjectionKindStatus, string(req.EventType)
+		if err := mission.ValidateInjection(req.EventType, req.Value); err != nil {
+			logger.Error("invalid injection", zap.Error(err))
+			return http.StatusBadRequest, WrapResp(err.Error())
+		}
+		audit.EventID, injectErr = h.missionService.InjectStatus(id, req.EventType, req.Value)
+	}
+
+	audit.Result = "success"
+	if injectErr != nil {
+		audit.Result = injectErr.Error()
+	}
+	if err := h.db.AddInjectionAudit(audit); err != nil {
+		logger.Error("failed to add injection audit", zap.Error(err))
+	}
+
+	if injectErr != nil {
+		logger.Error("failed to inject", zap.Uint("mission_id", id), zap.Error(injectErr))
+		if errors.Is(injectErr, mission.ErrMissionNotFound) {
+			return http.StatusNotFound, WrapResp("mission is not running")
+		}
+		return http.StatusInternalServerError, WrapResp("failed to inject")
+	}
+	logger.Info("injected", zap.Uint("mission_id", id), zap.String("kind", string(audit.Kind)), zap.String("target", audit.Target))
+	return http.StatusOK, WrapRespWithData("success", audit)
+}
+
+// GetAuditList 获取注入记录，可以通过 mission_id 过滤
+func (h *InstructorHandler) GetAuditList(c echo.Context) (err error) {
+	logger := ExtractLogger(c)
+	var missionID uint64
+	if idStr := c.QueryParam("mission_id"); idStr != "" {
+		if missionID, err = strconv.ParseUint(idStr, 10, 64); err != nil {
+			logger.Error("invalid mission id", zap.Error(err))
+			return c.JSON(http.StatusBadRequest, WrapResp("invalid mission id"))
+		}
+	}
+	list, err := h.db.GetInjectionAuditList(uint(missionID))
+	if err != nil {
+		logger.Error("failed to get injection audit list", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, WrapResp("failed to get injection audit list"))
+	}
+	return c.JSON(http.StatusOK, WrapRespWithData("success", list))
+}
@@ -1,30 +1,126 @@
 package controller
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/eli-yip/rocket-control/db"
+	"github.com/eli-yip/rocket-control/mission"
 	"github.com/labstack/echo/v4"
 	"github.com/rezakhademix/govalidator/v2"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 type (
+	// MissionConfigRequest 是创建任务和任务模板共用的可选配置
+	MissionConfigRequest struct {
+		Desc         string            `json:"desc"`
+		SuccessRate  float64           `json:"success_rate"` // 0-100，不设置时使用 db.DefaultSuccessRate
+		PhysicsModel string            `json:"physics_model"`
+		Hazard       *db.HazardConfig  `json:"hazard"`      // 事故率的调节方式，不设置时使用 db.DefaultHazardConfig
+		ScenarioID   uint              `json:"scenario_id"` // 训练剧本
+		Accidents    []string          `json:"accidents"`   // 可能随机发生的事故名称，不设置时使用整个事故目录
+		Programs     []uint            `json:"programs"`    // 可以执行的自定义程序，不设置时不限制
+		Setting      *db.RocketSetting `json:"setting"`     // 初始设置
+		Status       *db.RocketStatus  `json:"status"`      // 初始状态
+
+		Criteria *db.SuccessCriteria `json:"criteria"` // 任务结束时的评估标准
+	}
+
 	AddMissionRequest struct {
-		Name     string `json:"name"`
-		Duration int    `json:"duration"`
+		Name       string `json:"name"`
+		Duration   int    `json:"duration"`    // 使用模板时可以不设置
+		TemplateID uint   `json:"template_id"` // 任务模板，请求中的其他配置会覆盖模板
+
+		StartTime *time.Time `json:"start_time"` // 设置后调度器在该时间自动开始任务，并在 Duration 分钟后结束
+
+		MissionConfigRequest
+	}
 
-		Desc        string  `json:"desc"`
-		SuccessRate float64 `json:"success_rate"`
+	CloneMissionRequest struct {
+		Name string `json:"name"`
 	}
 )
 
+// validate 校验配置，引用的物理模型、剧本、事故和程序必须存在
+func (r *MissionConfigRequest) validate(v govalidator.Validator, dbService db.Iface) {
+	v.CustomRule(r.SuccessRate >= 0 && r.SuccessRate <= 100, "success_rate", "success_rate must be between 0 and 100")
+	if r.Hazard != nil {
+		v.CustomRule(r.Hazard.Stabilizer >= 0 && r.Hazard.Thrust >= 0 && r.Hazard.Temperature >= 0, "hazard", "hazard factors must not be negative")
+	}
+	if r.PhysicsModel != "" {
+		_, err := mission.GetPhysicsModel(r.PhysicsModel)
+		v.CustomRule(err == nil, "physics_model", fmt.Sprintf("unknown physics model %q", r.PhysicsModel))
+	}
+	if r.ScenarioID != 0 {
+		_, err := dbService.GetScenario(r.ScenarioID)
+		v.CustomRule(err == nil, "scenario_id", "scenario not found")
+	}
+	for _, name := range r.Accidents {
+		_, err := dbService.GetAccidentByName(name)
+		v.CustomRule(err == nil, "accidents", fmt.Sprintf("accident %q not found", name))
+	}
+	for _, id := range r.Programs {
+		_, err := dbService.GetCustomProgram(id)
+		v.CustomRule(err == nil, "programs", fmt.Sprintf("program %d not found", id))
+	}
+	if r.Status != nil && r.Status.Phase != "" {
+		v.CustomRule(mission.ValidFlightPhase(r.Status.Phase), "status", fmt.Sprintf("invalid flight phase %q", r.Status.Phase))
+	}
+	if r.Criteria != nil {
+		err := mission.ValidateCriteria(r.Criteria)
+		v.CustomRule(err == nil, "criteria", fmt.Sprint(err))
+	}
+}
+
+// opts 返回请求中设置了的配置，没有设置的配置保持默认值或模板中的值
+func (r *MissionConfigRequest) opts() []db.MissionOptFunc {
+	opts := []db.MissionOptFunc{}
+	if r.Desc != "" {
+		opts = append(opts, db.WithMissionDesc(r.Desc))
+	}
+	if r.SuccessRate > 0 {
+		opts = append(opts, db.WithMissionSuccessRate(r.SuccessRate))
+	}
+	if r.PhysicsModel != "" {
+		opts = append(opts, db.WithMissionPhysicsModel(r.PhysicsModel))
+	}
+	if r.Hazard != nil {
+		opts = append(opts, db.WithMissionHazard(*r.Hazard))
+	}
+	if r.ScenarioID != 0 {
+		opts = append(opts, db.WithMissionScenario(r.ScenarioID))
+	}
+	if len(r.Accidents) > 0 {
+		opts = append(opts, db.WithMissionAccidents(r.Accidents))
+	}
+	if len(r.Programs) > 0 {
+		opts = append(opts, db.WithMissionPrograms(r.Programs))
+	}
+	if r.Setting != nil {
+		opts = append(opts, db.WithMissionInitialSetting(*r.Setting))
+	}
+	if r.Status != nil {
+		opts = append(opts, db.WithMissionInitialStatus(*r.Status))
+	}
+	if r.Criteria != nil {
+		opts = append(opts, db.WithMissionCriteria(*r.Criteria))
+	}
+	return opts
+}
+
 type MissionHandler struct {
-	db db.MissionIface
+	db             db.Iface
+	missionService *mission.MissionService
 }
 
-func NewMissionHandler(db db.MissionIface) *MissionHandler { return &MissionHandler{db: db} }
+func NewMissionHandler(db db.Iface, missionService *mission.MissionService) *MissionHandler {
+	return &MissionHandler{db: db, missionService: missionService}
+}
 
 func (h *MissionHandler) AddMission(c echo.Context) (err error) {
 	logger := ExtractLogger(c)
@@ -36,9 +132,29 @@ func (h *MissionHandler) AddMission(c echo.Context) (err error) {
 		return c.JSON(http.StatusBadRequest, WrapResp("failed to bind request"))
 	}
 
+	// 模板中的配置放在最前面，请求中设置了的配置会覆盖模板
+	opts := []db.MissionOptFunc{}
+	duration := req.Duration
+	if req.TemplateID != 0 {
+		t, err := h.db.GetMissionTemplate(req.TemplateID)
+		if err != nil {
+			logger.Error("failed to get mission template", zap.Uint("template_id", req.TemplateID), zap.Error(err))
+			return c.JSON(http.StatusBadRequest, WrapResp("mission template not found"))
+		}
+		opts = append(opts, db.WithMissionTemplate(t))
+		if duration == 0 {
+			duration = t.Duration
+		}
+	}
+
 	v := govalidator.New()
 	v.RequiredString(req.Name, "name", "name is required")
-	v.RequiredInt(req.Duration, "duration", "duration is required")
+	v.RequiredInt(duration, "duration", "duration is required")
+	if req.StartTime != nil {
+		v.CustomRule(!req.StartTime.IsZero(), "start_time", "invalid start_time")
+		v.CustomRule(duration > 0, "duration", "duration must be positive for scheduled mission")
+	}
+	req.validate(v, h.db)
 	if v.IsFailed() {
 		for k, v := range v.Errors() {
 			logger.Error("validation failed", zap.String("field", k), zap.String("error", v))
@@ -47,15 +163,12 @@ func (h *MissionHandler) AddMission(c echo.Context) (err error) {
 	}
 
 	// 支持可选参数
-	opts := []db.MissionOptFunc{}
-	if req.Desc != "" {
-		opts = append(opts, db.WithMissionDesc(req.Desc))
-	}
-	if req.SuccessRate > 0 {
-		opts = append(opts, db.WithMissionSuccessRate(req.SuccessRate))
+	opts = append(opts, req.opts()...)
+	if req.StartTime != nil {
+		opts = append(opts, db.WithMissionStartTime(*req.StartTime))
 	}
 
-	mission, err := h.db.AddMission(req.Name, user, req.Duration, opts...)
+	mission, err := h.db.AddMission(req.Name, user, duration, opts...)
 	if err != nil {
 		logger.Error("failed to add mission", zap.Error(err))
 		return c.JSON(http.StatusInternalServerError, WrapResp("failed to add mission"))
@@ -68,6 +181,48 @@ func (h *MissionHandler) AddMission(c echo.Context) (err error) {
 	return
 }
 
+// CloneMission 使用已有任务的配置创建一个新的任务，新任务从初始状态开始
+func (h *MissionHandler) CloneMission(c echo.Context) (err error) {
+	logger := ExtractLogger(c)
+	user := c.Get("username").(string)
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		logger.Error("invalid mission id", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, WrapResp("invalid mission id"))
+	}
+
+	var req CloneMissionRequest
+	if err = c.Bind(&req); err != nil {
+		logger.Error("failed to bind request", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, WrapResp("failed to bind request"))
+	}
+	v := govalidator.New()
+	v.RequiredString(req.Name, "name", "name is required")
+	if v.IsFailed() {
+		for k, v := range v.Errors() {
+			logger.Error("validation failed", zap.String("field", k), zap.String("error", v))
+		}
+		return c.JSON(http.StatusBadRequest, WrapRespWithData("validation failed", v.Errors()))
+	}
+
+	src, err := h.db.GetMission(uint(id))
+	if err != nil {
+		logger.Error("failed to get mission", zap.Error(err))
+		return c.JSON(http.StatusNotFound, WrapResp("mission not found"))
+	}
+	mission, err := h.db.AddMission(req.Name, user, src.Duration, db.WithMissionCloneOf(src))
+	if err != nil {
+		logger.Error("failed to clone mission", zap.Error(err))
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return c.JSON(http.StatusConflict, WrapResp("mission name already exists"))
+		}
+		return c.JSON(http.StatusInternalServerError, WrapResp("failed to clone mission"))
+	}
+	logger.Info("mission cloned", zap.Uint64("source", id), zap.Uint("mission_id", mission.ID))
+	return c.JSON(http.StatusOK, WrapRespWithData("success", mission))
+}
+
 func (h *MissionHandler) GetMission(c echo.Context) (err error) {
 	logger := ExtractLogger(c)
 	idStr := c.Param("id")
@@ -110,9 +265,50 @@ func (h *MissionHandler) UpdateMissionStatus(c echo.Context) (err error) {
 		logger.Error("failed to bind request", zap.Error(err))
 		return c.JSON(http.StatusBadRequest, WrapResp("failed to bind request"))
 	}
-	if err := h.db.UpdateMissionStatus(uint(id), db.MissionStatus(req.Status)); err != nil {
-		logger.Error("failed to update mission status", zap.Error(err))
-		return c.JSON(http.StatusInternalServerError, WrapResp("failed to update mission status"))
+	m, err := h.db.GetMission(uint(id))
+	if err != nil {
+		logger.Error("failed to get mission", zap.Error(err))
+		return c.JSON(http.StatusNotFound, WrapResp("mission not found"))
+	}
+	if m.Status.Ended() {
+		logger.Error("mission already ended", zap.Int("status", int(m.Status)))
+		return c.JSON(http.StatusConflict, WrapResp("mission already ended"))
+	}
+
+	// 暂停和恢复通过 pause、resume 事件完成；结束任务时最终状态由评估结果决定
+	switch status := db.MissionStatus(req.Status); status {
+	case db.MissionStatusInProgress:
+		if m.Status != db.MissionStatusPending {
+			return c.JSON(http.StatusConflict, WrapResp("mission already started"))
+		}
+		// 和调度器一样启动任务，数据库、内存中的任务状态和开始时间一起更新，任务按 Duration 结束
+		if err = h.missionService.StartMission(uint(id), time.Now()); err != nil {
+			logger.Error("failed to start mission", zap.Error(err))
+			return c.JSON(http.StatusInternalServerError, WrapResp("failed to start mission"))
+		}
+		return c.JSON(http.StatusOK, WrapResp("success"))
+	case db.MissionStatusCompleted, db.MissionStatusFailed:
+		result, err := h.missionService.EndMission(uint(id), time.Now())
+		if err != nil {
+			logger.Error("failed to end mission", zap.Error(err))
+			if errors.Is(err, mission.ErrMissionEnded) {
+				return c.JSON(http.StatusConflict, WrapResp("mission already ended"))
+			}
+			return c.JSON(http.StatusInternalServerError, WrapResp("failed to end mission"))
+		}
+		logger.Info("mission ended", zap.Uint64("mission_id", id), zap.Int("status", int(result.Status)), zap.Float64("score", result.Score))
+		return c.JSON(http.StatusOK, WrapRespWithData("success", result))
+	case db.MissionStatusCancelled:
+		if err = h.missionService.CancelMission(uint(id), time.Now()); err != nil {
+			logger.Error("failed to cancel mission", zap.Error(err))
+			if errors.Is(err, mission.ErrMissionEnded) {
+				return c.JSON(http.StatusConflict, WrapResp("mission already ended"))
+			}
+			return c.JSON(http.StatusInternalServerError, WrapResp("failed to cancel mission"))
+		}
+		return c.JSON(http.StatusOK, WrapResp("success"))
+	default:
+		logger.Error("invalid mission status", zap.Int("status", req.Status))
+		return c.JSON(http.StatusBadRequest, WrapResp("invalid mission status"))
 	}
-	return c.JSON(http.StatusOK, WrapResp("success"))
 }
@@ -0,0 +1,91 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/eli-yip/rocket-control/db"
+	"github.com/eli-yip/rocket-control/mission"
+)
+
+func newTestMissionHandler(statuses ...db.MissionStatus) (*MissionHandler, *mission.MissionService, *fakeDB) {
+	var missions []*db.Mission
+	for i, status := range statuses {
+		m := &db.Mission{Name: "test", CreatedBy: "commander", Status: status}
+		m.ID = uint(i + 1)
+		missions = append(missions, m)
+	}
+	fdb := newFakeDB(missions...)
+	ms := mission.NewMissionService(fdb)
+	return NewMissionHandler(fdb, ms), ms, fdb
+}
+
+func updateMissionStatus(t *testing.T, h *MissionHandler, id string, body string) int {
+	t.Helper()
+	c, rec := newTestContext(http.MethodPatch, "/api/v1/mission/"+id, body, "id", id)
+	if err := h.UpdateMissionStatus(c); err != nil {
+		t.Fatalf("UpdateMissionStatus returned error: %v", err)
+	}
+	return rec.Code
+}
+
+func statusBody(status db.MissionStatus) string {
+	return `{"status":` + strconv.Itoa(int(status)) + `}`
+}
+
+func TestUpdateMissionStatusRejectsInvalidRequests(t *testing.T) {
+	h, _, _ := newTestMissionHandler(db.MissionStatusPending, db.MissionStatusInProgress, db.MissionStatusCompleted)
+
+	for _, tc := range []struct {
+		name, id, body string
+		want           int
+	}{
+		{"invalid id", "x", statusBody(db.MissionStatusCancelled), http.StatusBadRequest},
+		{"invalid body", "1", `{"status":`, http.StatusBadRequest},
+		{"unknown status", "1", `{"status":99}`, http.StatusBadRequest},
+		{"back to pending", "2", statusBody(db.MissionStatusPending), http.StatusBadRequest},
+		{"pause", "2", statusBody(db.MissionStatusPaused), http.StatusBadRequest},
+		{"mission not found", "9", statusBody(db.MissionStatusCancelled), http.StatusNotFound},
+		{"already started", "2", statusBody(db.MissionStatusInProgress), http.StatusConflict},
+		{"already ended", "3", statusBody(db.MissionStatusCancelled), http.StatusConflict},
+	} {
+		if got := updateMissionStatus(t, h, tc.id, tc.body); got != tc.want {
+			t.Errorf("%s: status %d, want %d", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestUpdateMissionStatusTransitions(t *testing.T) {
+	h, ms, fdb := newTestMissionHandler(db.MissionStatusPending, db.MissionStatusPending)
+
+	// 开始任务：数据库中的状态和开始时间一起更新，任务固定在内存中
+	if got := updateMissionStatus(t, h, "1", statusBody(db.MissionStatusInProgress)); got != http.StatusOK {
+		t.Fatalf("start mission: status %d, want 200", got)
+	}
+	if m := fdb.mission(1); m.Status != db.MissionStatusInProgress || m.StartTime.IsZero() || !ms.Pinned(1) {
+		t.Fatalf("started mission: status %v, start time %v, pinned %v", m.Status, m.StartTime, ms.Pinned(1))
+	}
+	if got := updateMissionStatus(t, h, "1", statusBody(db.MissionStatusInProgress)); got != http.StatusConflict {
+		t.Errorf("start mission twice: status %d, want 409", got)
+	}
+
+	// 取消运行中的任务，不评估结果
+	if got := updateMissionStatus(t, h, "1", statusBody(db.MissionStatusCancelled)); got != http.StatusOK {
+		t.Fatalf("cancel mission: status %d, want 200", got)
+	}
+	if m := fdb.mission(1); m.Status != db.MissionStatusCancelled || m.Result != nil || ms.Pinned(1) {
+		t.Errorf("cancelled mission: status %v, result %v, pinned %v", m.Status, m.Result, ms.Pinned(1))
+	}
+	if got := updateMissionStatus(t, h, "1", statusBody(db.MissionStatusCompleted)); got != http.StatusConflict {
+		t.Errorf("end cancelled mission: status %d, want 409", got)
+	}
+
+	// 结束不在内存中的任务，最终状态由评估结果决定
+	if got := updateMissionStatus(t, h, "2", statusBody(db.MissionStatusFailed)); got != http.StatusOK {
+		t.Fatalf("end mission: status %d, want 200", got)
+	}
+	if m := fdb.mission(2); !m.Status.Ended() || m.Result == nil || m.Status != m.Result.Status {
+		t.Errorf("ended mission: status %v, result %+v", m.Status, m.Result)
+	}
+}
@@ -0,0 +1,137 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/eli-yip/rocket-control/db"
+	"github.com/labstack/echo/v4"
+	"github.com/rezakhademix/govalidator/v2"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type (
+	PresetRequest struct {
+		Name    string            `json:"name"`
+		Desc    string            `json:"desc"`
+		Setting *db.RocketSetting `json:"setting"`
+	}
+)
+
+type PresetHandler struct{ db db.PresetIface }
+
+func NewPresetHandler(db db.PresetIface) *PresetHandler { return &PresetHandler{db: db} }
+
+// bindPresetRequest 解析并校验请求，校验失败时已经写入响应，返回 nil
+func bindPresetRequest(c echo.Context, logger *zap.Logger) (*PresetRequest, error) {
+	var req PresetRequest
+	if err := c.Bind(&req); err != nil {
+		logger.Error("failed to bind request", zap.Error(err))
+		return nil, c.JSON(http.StatusBadRequest, WrapResp("failed to bind request"))
+	}
+
+	v := govalidator.New()
+	v.RequiredString(req.Name, "name", "name is required")
+	v.CustomRule(req.Setting != nil, "setting", "setting is required")
+	if v.IsFailed() {
+		for k, v := range v.Errors() {
+			logger.Error("validation failed", zap.String("field", k), zap.String("error", v))
+		}
+		return nil, c.JSON(http.StatusBadRequest, WrapRespWithData("validation failed", v.Errors()))
+	}
+	return &req, nil
+}
+
+func (h *PresetHandler) AddPreset(c echo.Context) (err error) {
+	logger := ExtractLogger(c)
+
+	req, err := bindPresetRequest(c, logger)
+	if req == nil {
+		return err
+	}
+
+	preset, err := h.db.AddPreset(req.Name, req.Desc, *req.Setting)
+	if err != nil {
+		logger.Error("failed to add preset", zap.Error(err))
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return c.JSON(http.StatusConflict, WrapResp("preset name already exists"))
+		}
+		return c.JSON(http.StatusInternalServerError, WrapResp("failed to add preset"))
+	}
+	return c.JSON(http.StatusOK, WrapRespWithData("success", preset))
+}
+
+func (h *PresetHandler) GetPreset(c echo.Context) (err error) {
+	logger := ExtractLogger(c)
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		logger.Error("invalid preset id", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, WrapResp("invalid preset id"))
+	}
+	preset, err := h.db.GetPreset(uint(id))
+	if err != nil {
+		logger.Error("failed to get preset", zap.Error(err))
+		return c.JSON(http.StatusNotFound, WrapResp("preset not found"))
+	}
+	return c.JSON(http.StatusOK, WrapRespWithData("success", preset))
+}
+
+func (h *PresetHandler) GetPresetList(c echo.Context) (err error) {
+	logger := ExtractLogger(c)
+	list, err := h.db.GetPresetList()
+	if err != nil {
+		logger.Error("failed to get preset list", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, WrapResp("failed to get preset list"))
+	}
+	return c.JSON(http.StatusOK, WrapRespWithData("success", list))
+}
+
+func (h *PresetHandler) UpdatePreset(c echo.Context) (err error) {
+	logger := ExtractLogger(c)
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		logger.Error("invalid preset id", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, WrapResp("invalid preset id"))
+	}
+	if _, err = h.db.GetPreset(uint(id)); err != nil {
+		logger.Error("failed to get preset", zap.Error(err))
+		return c.JSON(http.StatusNotFound, WrapResp("preset not found"))
+	}
+
+	req, err := bindPresetRequest(c, logger)
+	if req == nil {
+		return err
+	}
+
+	if err = h.db.UpdatePreset(uint(id), req.Name, req.Desc, *req.Setting); err != nil {
+		logger.Error("failed to update preset", zap.Error(err))
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return c.JSON(http.StatusConflict, WrapResp("preset name already exists"))
+		}
+		return c.JSON(http.StatusInternalServerError, WrapResp("failed to update preset"))
+	}
+	return c.JSON(http.StatusOK, WrapResp("success"))
+}
+
+func (h *PresetHandler) DeletePreset(c echo.Context) (err error) {
+	logger := ExtractLogger(c)
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		logger.Error("invalid preset id", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, WrapResp("invalid preset id"))
+	}
+	if _, err = h.db.GetPreset(uint(id)); err != nil {
+		logger.Error("failed to get preset", zap.Error(err))
+		return c.JSON(http.StatusNotFound, WrapResp("preset not found"))
+	}
+	if err = h.db.DeletePreset(uint(id)); err != nil {
+		logger.Error("failed to delete preset", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, WrapResp("failed to delete preset"))
+	}
+	return c.JSON(http.StatusOK, WrapResp("success"))
+}
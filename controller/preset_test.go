@@ -0,0 +1,55 @@
+package controller
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestPresetHandlers(t *testing.T) {
+	fdb := newFakeDB()
+	h := NewPresetHandler(fdb)
+
+	for _, tc := range []struct {
+		name, body string
+		want       int
+	}{
+		{"valid", `{"name":"cruise","setting":{"Thrust":60,"Power":true}}`, http.StatusOK},
+		{"second", `{"name":"landing","setting":{"Thrust":20}}`, http.StatusOK},
+		{"duplicate name", `{"name":"cruise","setting":{}}`, http.StatusConflict},
+		{"missing name", `{"setting":{}}`, http.StatusBadRequest},
+		{"missing setting", `{"name":"empty"}`, http.StatusBadRequest},
+	} {
+		c, rec := newTestContext(http.MethodPost, "/api/v1/preset", tc.body)
+		if err := h.AddPreset(c); err != nil {
+			t.Fatalf("%s: AddPreset returned error: %v", tc.name, err)
+		}
+		if rec.Code != tc.want {
+			t.Errorf("%s: status %d, want %d: %s", tc.name, rec.Code, tc.want, rec.Body)
+		}
+	}
+	if p, _ := fdb.GetPreset(1); p == nil || p.Thrust != 60 || !p.Power {
+		t.Fatalf("preset setting not saved: %+v", p)
+	}
+
+	for _, tc := range []struct {
+		name, id, body string
+		want           int
+	}{
+		{"update", "1", `{"name":"cruise","setting":{"Thrust":70}}`, http.StatusOK},
+		{"rename to existing", "1", `{"name":"landing","setting":{}}`, http.StatusConflict},
+		{"missing setting", "1", `{"name":"cruise"}`, http.StatusBadRequest},
+		{"not found", "9", `{"name":"cruise","setting":{}}`, http.StatusNotFound},
+		{"invalid id", "x", `{"name":"cruise","setting":{}}`, http.StatusBadRequest},
+	} {
+		c, rec := newTestContext(http.MethodPut, "/api/v1/preset/"+tc.id, tc.body, "id", tc.id)
+		if err := h.UpdatePreset(c); err != nil {
+			t.Fatalf("%s: UpdatePreset returned error: %v", tc.name, err)
+		}
+		if rec.Code != tc.want {
+			t.Errorf("%s: status %d, want %d: %s", tc.name, rec.Code, tc.want, rec.Body)
+		}
+	}
+	if p, _ := fdb.GetPreset(1); p.Thrust != 70 || p.Power {
+		t.Errorf("preset setting not replaced: %+v", p.RocketSetting)
+	}
+}
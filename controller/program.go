@@ -0,0 +1,278 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/eli-yip/rocket-control/db"
+	"github.com/eli-yip/rocket-control/mission"
+	"github.com/labstack/echo/v4"
+	"github.com/rezakhademix/govalidator/v2"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type (
+	ProgramRequest struct {
+		Name  string          `json:"name"`
+		Desc  string          `json:"desc"`
+		Steps db.ProgramSteps `json:"steps"`
+	}
+
+	// SimulateProgramRequest 描述一次 dry-run，ProgramID 和 Steps 二选一。
+	// 起始状态依次使用 Setting/Status、MissionID 对应任务当前的 SystemState、默认值。
+	SimulateProgramRequest struct {
+		ProgramID    uint              `json:"program_id"`
+		Steps        db.ProgramSteps   `json:"steps"`
+		MissionID    uint              `json:"mission_id"`
+		Setting      *db.RocketSetting `json:"setting"`
+		Status       *db.RocketStatus  `json:"status"`
+		PhysicsModel string            `json:"physics_model"`
+	}
+
+	// ProgramResp 自定义程序的返回结构，Steps 为解析后的步骤
+	ProgramResp struct {
+		ID        uint            `json:"id"`
+		CreatedAt time.Time       `json:"created_at"`
+		UpdatedAt time.Time       `json:"updated_at"`
+		IsSystem  bool            `json:"is_system"`
+		Name      string          `json:"name"`
+		Desc      string          `json:"desc"`
+		Steps     db.ProgramSteps `json:"steps"`
+	}
+)
+
+type ProgramHandler struct{ db db.Iface }
+
+func NewProgramHandler(db db.Iface) *ProgramHandler { return &ProgramHandler{db: db} }
+
+func newProgramResp(p *db.CustomProgram) (*ProgramResp, error) {
+	steps, err := p.ProgramSteps()
+	if err != nil {
+		return nil, err
+	}
+	return &ProgramResp{
+		ID:        p.ID,
+		CreatedAt: p.CreatedAt,
+		UpdatedAt: p.UpdatedAt,
+		IsSystem:  p.IsSystem,
+		Name:      p.Name,
+		Desc:      p.Desc,
+		Steps:     steps,
+	}, nil
+}
+
+// bindProgramRequest 解析并校验请求，校验失败时已经写入响应，返回 nil
+func bindProgramRequest(c echo.Context, logger *zap.Logger) (*ProgramRequest, error) {
+	var req ProgramRequest
+	if err := c.Bind(&req); err != nil {
+		logger.Error("failed to bind request", zap.Error(err))
+		return nil, c.JSON(http.StatusBadRequest, WrapResp("failed to bind request"))
+	}
+
+	v := govalidator.New()
+	v.RequiredString(req.Name, "name", "name is required")
+	if v.IsFailed() {
+		for k, v := range v.Errors() {
+			logger.Error("validation failed", zap.String("field", k), zap.String("error", v))
+		}
+		return nil, c.JSON(http.StatusBadRequest, WrapRespWithData("validation failed", v.Errors()))
+	}
+	if err := mission.ValidateProgramSteps(req.Steps); err != nil {
+		logger.Error("invalid program steps", zap.Error(err))
+		return nil, c.JSON(http.StatusBadRequest, WrapResp(err.Error()))
+	}
+	return &req, nil
+}
+
+func (h *ProgramHandler) AddProgram(c echo.Context) (err error) {
+	logger := ExtractLogger(c)
+
+	req, err := bindProgramRequest(c, logger)
+	if req == nil {
+		return err
+	}
+
+	program, err := h.db.AddCustomProgram(req.Name, req.Desc, req.Steps)
+	if err != nil {
+		logger.Error("failed to add program", zap.Error(err))
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return c.JSON(http.StatusConflict, WrapResp("program name already exists"))
+		}
+		return c.JSON(http.StatusInternalServerError, WrapResp("failed to add program"))
+	}
+	resp, err := newProgramResp(program)
+	if err != nil {
+		logger.Error("failed to parse program steps", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, WrapResp("failed to parse program steps"))
+	}
+	return c.JSON(http.StatusOK, WrapRespWithData("success", resp))
+}
+
+func (h *ProgramHandler) GetProgram(c echo.Context) (err error) {
+	logger := ExtractLogger(c)
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		logger.Error("invalid program id", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, WrapResp("invalid program id"))
+	}
+	program, err := h.db.GetCustomProgram(uint(id))
+	if err != nil {
+		logger.Error("failed to get program", zap.Error(err))
+		return c.JSON(http.StatusNotFound, WrapResp("program not found"))
+	}
+	resp, err := newProgramResp(program)
+	if err != nil {
+		logger.Error("failed to parse program steps", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, WrapResp("failed to parse program steps"))
+	}
+	return c.JSON(http.StatusOK, WrapRespWithData("success", resp))
+}
+
+func (h *ProgramHandler) GetProgramList(c echo.Context) (err error) {
+	logger := ExtractLogger(c)
+	list, err := h.db.GetCustomProgramList()
+	if err != nil {
+		logger.Error("failed to get program list", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, WrapResp("failed to get program list"))
+	}
+	resp := make([]*ProgramResp, 0, len(list))
+	for _, p := range list {
+		r, err := newProgramResp(p)
+		if err != nil {
+			logger.Error("failed to parse program steps", zap.Uint("id", p.ID), zap.Error(err))
+			return c.JSON(http.StatusInternalServerError, WrapResp("failed to parse program steps"))
+		}
+		resp = append(resp, r)
+	}
+	return c.JSON(http.StatusOK, WrapRespWithData("success", resp))
+}
+
+// getUserProgram 获取可以被用户修改的程序，系统预设程序不允许修改，失败时已经写入响应，返回 nil
+func (h *ProgramHandler) getUserProgram(c echo.Context, logger *zap.Logger) (*db.CustomProgram, error) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		logger.Error("invalid program id", zap.Error(err))
+		return nil, c.JSON(http.StatusBadRequest, WrapResp("invalid program id"))
+	}
+	program, err := h.db.GetCustomProgram(uint(id))
+	if err != nil {
+		logger.Error("failed to get program", zap.Error(err))
+		return nil, c.JSON(http.StatusNotFound, WrapResp("program not found"))
+	}
+	if program.IsSystem {
+		logger.Error("system program cannot be modified", zap.Uint("id", program.ID))
+		return nil, c.JSON(http.StatusForbidden, WrapResp("system program cannot be modified"))
+	}
+	return program, nil
+}
+
+func (h *ProgramHandler) UpdateProgram(c echo.Context) (err error) {
+	logger := ExtractLogger(c)
+
+	program, err := h.getUserProgram(c, logger)
+	if program == nil {
+		return err
+	}
+	req, err := bindProgramRequest(c, logger)
+	if req == nil {
+		return err
+	}
+
+	if err = h.db.UpdateCustomProgram(program.ID, req.Name, req.Desc, req.Steps); err != nil {
+		logger.Error("failed to update program", zap.Error(err))
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return c.JSON(http.StatusConflict, WrapResp("program name already exists"))
+		}
+		return c.JSON(http.StatusInternalServerError, WrapResp("failed to update program"))
+	}
+	return c.JSON(http.StatusOK, WrapResp("success"))
+}
+
+func (h *ProgramHandler) DeleteProgram(c echo.Context) (err error) {
+	logger := ExtractLogger(c)
+
+	program, err := h.getUserProgram(c, logger)
+	if program == nil {
+		return err
+	}
+	if err = h.db.DeleteCustomProgram(program.ID); err != nil {
+		logger.Error("failed to delete program", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, WrapResp("failed to delete program"))
+	}
+	return c.JSON(http.StatusOK, WrapResp("success"))
+}
+
+// SimulateProgram 在不影响任何任务的情况下执行程序，返回预计的状态变化、失败的步骤和触发的阈值
+func (h *ProgramHandler) SimulateProgram(c echo.Context) (err error) {
+	logger := ExtractLogger(c)
+	user := c.Get("username").(string)
+
+	var req SimulateProgramRequest
+	if err = c.Bind(&req); err != nil {
+		logger.Error("failed to bind request", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, WrapResp("failed to bind request"))
+	}
+
+	steps := req.Steps
+	if req.ProgramID != 0 {
+		program, err := h.db.GetCustomProgram(req.ProgramID)
+		if err != nil {
+			logger.Error("failed to get program", zap.Error(err))
+			return c.JSON(http.StatusNotFound, WrapResp("program not found"))
+		}
+		if steps, err = program.ProgramSteps(); err != nil {
+			logger.Error("failed to parse program steps", zap.Error(err))
+			return c.JSON(http.StatusInternalServerError, WrapResp("failed to parse program steps"))
+		}
+	}
+	if err = mission.ValidateProgramSteps(steps); err != nil {
+		logger.Error("invalid program steps", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, WrapResp(err.Error()))
+	}
+
+	in := mission.DryRunInput{
+		Steps:        steps,
+		Setting:      db.DefaultRocketSetting,
+		Status:       db.DefaultRocketStatus,
+		PhysicsModel: req.PhysicsModel,
+		CreatedBy:    user,
+	}
+	if req.MissionID != 0 {
+		m, err := h.db.GetMission(req.MissionID)
+		if err != nil {
+			logger.Error("failed to get mission", zap.Error(err))
+			return c.JSON(http.StatusNotFound, WrapResp("mission not found"))
+		}
+		if in.PhysicsModel == "" {
+			in.PhysicsModel = m.PhysicsModel
+		}
+		state, err := h.db.GetSystemState(m.ID)
+		if err == nil {
+			in.Setting, in.Status = state.RocketSetting, state.RocketStatus
+		} else if errors.Is(err, gorm.ErrRecordNotFound) {
+			// 任务还没有运行过，使用任务的初始状态
+			in.Setting, in.Status = m.InitialState()
+		} else {
+			logger.Error("failed to get system state", zap.Error(err))
+			return c.JSON(http.StatusInternalServerError, WrapResp("failed to get system state"))
+		}
+	}
+	if req.Setting != nil {
+		in.Setting = *req.Setting
+	}
+	if req.Status != nil {
+		in.Status = *req.Status
+	}
+
+	result, err := mission.DryRun(h.db, in)
+	if err != nil {
+		logger.Error("failed to simulate program", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, WrapResp(err.Error()))
+	}
+	return c.JSON(http.StatusOK, WrapRespWithData("success", result))
+}
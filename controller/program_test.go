@@ -0,0 +1,70 @@
+package controller
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestAddProgramValidatesSteps(t *testing.T) {
+	h := NewProgramHandler(newFakeDB())
+
+	for _, tc := range []struct {
+		name, body string
+		want       int
+	}{
+		{"valid", `{"name":"ignite","steps":[{"event_type":"thrust","value":"80"},{"event_type":"power","value":"true"}]}`, http.StatusOK},
+		{"duplicate name", `{"name":"ignite","steps":[{"event_type":"thrust","value":"80"}]}`, http.StatusConflict},
+		{"missing name", `{"steps":[{"event_type":"thrust","value":"80"}]}`, http.StatusBadRequest},
+		{"no steps", `{"name":"empty","steps":[]}`, http.StatusBadRequest},
+		{"not a number", `{"name":"bad","steps":[{"event_type":"thrust","value":"max"}]}`, http.StatusBadRequest},
+		{"not a bool", `{"name":"bad","steps":[{"event_type":"power","value":"on"}]}`, http.StatusBadRequest},
+		{"negative duration", `{"name":"bad","steps":[{"event_type":"thrust","value":"80","duration":-1}]}`, http.StatusBadRequest},
+		{"unknown step", `{"name":"bad","steps":[{"event_type":"warp","value":"9"}]}`, http.StatusBadRequest},
+	} {
+		c, rec := newTestContext(http.MethodPost, "/api/v1/program", tc.body)
+		if err := h.AddProgram(c); err != nil {
+			t.Fatalf("%s: AddProgram returned error: %v", tc.name, err)
+		}
+		if rec.Code != tc.want {
+			t.Errorf("%s: status %d, want %d: %s", tc.name, rec.Code, tc.want, rec.Body)
+		}
+	}
+}
+
+func TestSystemProgramCannotBeModified(t *testing.T) {
+	fdb := newFakeDB()
+	h := NewProgramHandler(fdb)
+	system, _ := fdb.AddCustomProgram("launch", "", nil)
+	system.IsSystem = true
+	fdb.AddCustomProgram("custom", "", nil)
+
+	body := `{"name":"renamed","steps":[{"event_type":"thrust","value":"80"}]}`
+	for _, tc := range []struct {
+		id   string
+		want int
+	}{
+		{"1", http.StatusForbidden},
+		{"2", http.StatusOK},
+		{"9", http.StatusNotFound},
+		{"x", http.StatusBadRequest},
+	} {
+		c, rec := newTestContext(http.MethodPut, "/api/v1/program/"+tc.id, body, "id", tc.id)
+		if err := h.UpdateProgram(c); err != nil {
+			t.Fatalf("UpdateProgram returned error: %v", err)
+		}
+		if rec.Code != tc.want {
+			t.Errorf("update program %s: status %d, want %d", tc.id, rec.Code, tc.want)
+		}
+	}
+
+	c, rec := newTestContext(http.MethodDelete, "/api/v1/program/1", "", "id", "1")
+	if err := h.DeleteProgram(c); err != nil {
+		t.Fatalf("DeleteProgram returned error: %v", err)
+	}
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("delete system program: status %d, want 403", rec.Code)
+	}
+	if p, _ := fdb.GetCustomProgram(1); p == nil || p.Name != "launch" {
+		t.Error("system program was modified")
+	}
+}
@@ -2,6 +2,7 @@ package controller
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
@@ -44,6 +45,10 @@ func (h *RocketController) JoinMission(c echo.Context) (err error) {
 	}
 	missionID := uint(missionIDUint64)
 	messageCh, err := h.missionService.JoinMission(missionID, user)
+	if errors.Is(err, mission.ErrMissionNotFound) {
+		logger.Error("mission not found", zap.Uint("mission_id", missionID))
+		return c.JSON(http.StatusNotFound, WrapResp("mission not found"))
+	}
 	if err != nil {
 		logger.Error("failed to join mission", zap.Error(err))
 		return c.JSON(http.StatusInternalServerError, WrapResp("failed to join mission"))
@@ -62,6 +67,8 @@ func (h *RocketController) JoinMission(c echo.Context) (err error) {
 	defer ws.Close(websocket.StatusNormalClosure, "")
 
 	go func() {
+		// 连接断开后结束写循环，从而离开任务
+		defer cancel()
 		for {
 			select {
 			case <-ctx.Done():
@@ -69,9 +76,12 @@ func (h *RocketController) JoinMission(c echo.Context) (err error) {
 			default:
 				var action models.Action
 				if err := wsjson.Read(ctx, ws, &action); err != nil {
-					// TODO: handle error
+					if websocket.CloseStatus(err) == -1 {
+						logger.Error("failed to read message from websocket", zap.Error(err))
+					}
+					return
 				}
-				go h.missionService.AddEvent(missionID, action.ToEvent(user))
+				h.missionService.SubmitAction(missionID, action.ToEvent(user))
 			}
 		}
 	}()
@@ -80,7 +90,10 @@ func (h *RocketController) JoinMission(c echo.Context) (err error) {
 		select {
 		case <-ctx.Done():
 			return nil
-		case m := <-messageCh:
+		case m, ok := <-messageCh:
+			if !ok {
+				return nil
+			}
 			writeCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
 			err = wsjson.Write(writeCtx, ws, m)
 			cancel()
@@ -0,0 +1,157 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/eli-yip/rocket-control/db"
+	"github.com/eli-yip/rocket-control/mission"
+	"github.com/labstack/echo/v4"
+	"github.com/rezakhademix/govalidator/v2"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type (
+	ScenarioRequest struct {
+		Name                   string             `json:"name"`
+		Desc                   string             `json:"desc"`
+		DisableRandomAccidents bool               `json:"disable_random_accidents"`
+		Events                 []db.ScenarioEvent `json:"events"`
+	}
+)
+
+type ScenarioHandler struct{ db db.Iface }
+
+func NewScenarioHandler(db db.Iface) *ScenarioHandler { return &ScenarioHandler{db: db} }
+
+// bindScenarioRequest 解析并校验请求，返回待保存的剧本，校验失败时已经写入响应，返回 nil
+func (h *ScenarioHandler) bindScenarioRequest(c echo.Context, logger *zap.Logger) (*db.Scenario, error) {
+	var req ScenarioRequest
+	if err := c.Bind(&req); err != nil {
+		logger.Error("failed to bind request", zap.Error(err))
+		return nil, c.JSON(http.StatusBadRequest, WrapResp("failed to bind request"))
+	}
+
+	v := govalidator.New()
+	v.RequiredString(req.Name, "name", "name is required")
+	v.CustomRule(len(req.Events) > 0, "events", "events is required")
+	if v.IsFailed() {
+		for k, v := range v.Errors() {
+			logger.Error("validation failed", zap.String("field", k), zap.String("error", v))
+		}
+		return nil, c.JSON(http.StatusBadRequest, WrapRespWithData("validation failed", v.Errors()))
+	}
+	if err := mission.ValidateScenarioEvents(req.Events); err != nil {
+		logger.Error("invalid scenario events", zap.Error(err))
+		return nil, c.JSON(http.StatusBadRequest, WrapResp(err.Error()))
+	}
+	for _, ev := range req.Events {
+		if ev.Accident == "" {
+			continue
+		}
+		if _, err := h.db.GetAccidentByName(ev.Accident); err != nil {
+			logger.Error("failed to get scenario accident", zap.String("accident", ev.Accident), zap.Error(err))
+			return nil, c.JSON(http.StatusBadRequest, WrapResp(fmt.Sprintf("accident %q not found", ev.Accident)))
+		}
+	}
+
+	return &db.Scenario{
+		Name:                   req.Name,
+		Desc:                   req.Desc,
+		DisableRandomAccidents: req.DisableRandomAccidents,
+		Events:                 req.Events,
+	}, nil
+}
+
+func (h *ScenarioHandler) AddScenario(c echo.Context) (err error) {
+	logger := ExtractLogger(c)
+
+	sc, err := h.bindScenarioRequest(c, logger)
+	if sc == nil {
+		return err
+	}
+	if err = h.db.AddScenario(sc); err != nil {
+		logger.Error("failed to add scenario", zap.Error(err))
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return c.JSON(http.StatusConflict, WrapResp("scenario name already exists"))
+		}
+		return c.JSON(http.StatusInternalServerError, WrapResp("failed to add scenario"))
+	}
+	return c.JSON(http.StatusOK, WrapRespWithData("success", sc))
+}
+
+func (h *ScenarioHandler) GetScenario(c echo.Context) (err error) {
+	logger := ExtractLogger(c)
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		logger.Error("invalid scenario id", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, WrapResp("invalid scenario id"))
+	}
+	sc, err := h.db.GetScenario(uint(id))
+	if err != nil {
+		logger.Error("failed to get scenario", zap.Error(err))
+		return c.JSON(http.StatusNotFound, WrapResp("scenario not found"))
+	}
+	return c.JSON(http.StatusOK, WrapRespWithData("success", sc))
+}
+
+func (h *ScenarioHandler) GetScenarioList(c echo.Context) (err error) {
+	logger := ExtractLogger(c)
+	list, err := h.db.GetScenarioList()
+	if err != nil {
+		logger.Error("failed to get scenario list", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, WrapResp("failed to get scenario list"))
+	}
+	return c.JSON(http.StatusOK, WrapRespWithData("success", list))
+}
+
+func (h *ScenarioHandler) UpdateScenario(c echo.Context) (err error) {
+	logger := ExtractLogger(c)
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		logger.Error("invalid scenario id", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, WrapResp("invalid scenario id"))
+	}
+	if _, err = h.db.GetScenario(uint(id)); err != nil {
+		logger.Error("failed to get scenario", zap.Error(err))
+		return c.JSON(http.StatusNotFound, WrapResp("scenario not found"))
+	}
+
+	sc, err := h.bindScenarioRequest(c, logger)
+	if sc == nil {
+		return err
+	}
+	sc.ID = uint(id)
+	if err = h.db.UpdateScenario(sc); err != nil {
+		logger.Error("failed to update scenario", zap.Error(err))
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return c.JSON(http.StatusConflict, WrapResp("scenario name already exists"))
+		}
+		return c.JSON(http.StatusInternalServerError, WrapResp("failed to update scenario"))
+	}
+	return c.JSON(http.StatusOK, WrapResp("success"))
+}
+
+func (h *ScenarioHandler) DeleteScenario(c echo.Context) (err error) {
+	logger := ExtractLogger(c)
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		logger.Error("invalid scenario id", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, WrapResp("invalid scenario id"))
+	}
+	if _, err = h.db.GetScenario(uint(id)); err != nil {
+		logger.Error("failed to get scenario", zap.Error(err))
+		return c.JSON(http.StatusNotFound, WrapResp("scenario not found"))
+	}
+	if err = h.db.DeleteScenario(uint(id)); err != nil {
+		logger.Error("failed to delete scenario", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, WrapResp("failed to delete scenario"))
+	}
+	return c.JSON(http.StatusOK, WrapResp("success"))
+}
@@ -0,0 +1,151 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/eli-yip/rocket-control/db"
+	"github.com/labstack/echo/v4"
+	"github.com/rezakhademix/govalidator/v2"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type (
+	TemplateRequest struct {
+		Name     string `json:"name"`
+		Duration int    `json:"duration"`
+
+		MissionConfigRequest
+	}
+)
+
+type TemplateHandler struct{ db db.Iface }
+
+func NewTemplateHandler(db db.Iface) *TemplateHandler { return &TemplateHandler{db: db} }
+
+// bindTemplateRequest 解析并校验请求，返回待保存的模板，校验失败时已经写入响应，返回 nil
+func (h *TemplateHandler) bindTemplateRequest(c echo.Context, logger *zap.Logger) (*db.MissionTemplate, error) {
+	var req TemplateRequest
+	if err := c.Bind(&req); err != nil {
+		logger.Error("failed to bind request", zap.Error(err))
+		return nil, c.JSON(http.StatusBadRequest, WrapResp("failed to bind request"))
+	}
+
+	v := govalidator.New()
+	v.RequiredString(req.Name, "name", "name is required")
+	v.RequiredInt(req.Duration, "duration", "duration is required")
+	req.validate(v, h.db)
+	if v.IsFailed() {
+		for k, v := range v.Errors() {
+			logger.Error("validation failed", zap.String("field", k), zap.String("error", v))
+		}
+		return nil, c.JSON(http.StatusBadRequest, WrapRespWithData("validation failed", v.Errors()))
+	}
+
+	return &db.MissionTemplate{
+		Name:         req.Name,
+		Desc:         req.Desc,
+		Duration:     req.Duration,
+		SuccessRate:  req.SuccessRate,
+		PhysicsModel: req.PhysicsModel,
+		Hazard:       req.Hazard,
+		ScenarioID:   req.ScenarioID,
+		Accidents:    req.Accidents,
+		Programs:     req.Programs,
+		Setting:      req.Setting,
+		Status:       req.Status,
+		Criteria:     req.Criteria,
+	}, nil
+}
+
+func (h *TemplateHandler) AddTemplate(c echo.Context) (err error) {
+	logger := ExtractLogger(c)
+
+	t, err := h.bindTemplateRequest(c, logger)
+	if t == nil {
+		return err
+	}
+	if err = h.db.AddMissionTemplate(t); err != nil {
+		logger.Error("failed to add mission template", zap.Error(err))
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return c.JSON(http.StatusConflict, WrapResp("mission template name already exists"))
+		}
+		return c.JSON(http.StatusInternalServerError, WrapResp("failed to add mission template"))
+	}
+	return c.JSON(http.StatusOK, WrapRespWithData("success", t))
+}
+
+func (h *TemplateHandler) GetTemplate(c echo.Context) (err error) {
+	logger := ExtractLogger(c)
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		logger.Error("invalid mission template id", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, WrapResp("invalid mission template id"))
+	}
+	t, err := h.db.GetMissionTemplate(uint(id))
+	if err != nil {
+		logger.Error("failed to get mission template", zap.Error(err))
+		return c.JSON(http.StatusNotFound, WrapResp("mission template not found"))
+	}
+	return c.JSON(http.StatusOK, WrapRespWithData("success", t))
+}
+
+func (h *TemplateHandler) GetTemplateList(c echo.Context) (err error) {
+	logger := ExtractLogger(c)
+	list, err := h.db.GetMissionTemplateList()
+	if err != nil {
+		logger.Error("failed to get mission template list", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, WrapResp("failed to get mission template list"))
+	}
+	return c.JSON(http.StatusOK, WrapRespWithData("success", list))
+}
+
+func (h *TemplateHandler) UpdateTemplate(c echo.Context) (err error) {
+	logger := ExtractLogger(c)
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		logger.Error("invalid mission template id", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, WrapResp("invalid mission template id"))
+	}
+	if _, err = h.db.GetMissionTemplate(uint(id)); err != nil {
+		logger.Error("failed to get mission template", zap.Error(err))
+		return c.JSON(http.StatusNotFound, WrapResp("mission template not found"))
+	}
+
+	t, err := h.bindTemplateRequest(c, logger)
+	if t == nil {
+		return err
+	}
+	t.ID = uint(id)
+	if err = h.db.UpdateMissionTemplate(t); err != nil {
+		logger.Error("failed to update mission template", zap.Error(err))
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return c.JSON(http.StatusConflict, WrapResp("mission template name already exists"))
+		}
+		return c.JSON(http.StatusInternalServerError, WrapResp("failed to update mission template"))
+	}
+	return c.JSON(http.StatusOK, WrapResp("success"))
+}
+
+func (h *TemplateHandler) DeleteTemplate(c echo.Context) (err error) {
+	logger := ExtractLogger(c)
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		logger.Error("invalid mission template id", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, WrapResp("invalid mission template id"))
+	}
+	if _, err = h.db.GetMissionTemplate(uint(id)); err != nil {
+		logger.Error("failed to get mission template", zap.Error(err))
+		return c.JSON(http.StatusNotFound, WrapResp("mission template not found"))
+	}
+	if err = h.db.DeleteMissionTemplate(uint(id)); err != nil {
+		logger.Error("failed to delete mission template", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, WrapResp("failed to delete mission template"))
+	}
+	return c.JSON(http.StatusOK, WrapResp("success"))
+}
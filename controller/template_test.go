@@ -0,0 +1,118 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/eli-yip/rocket-control/db"
+)
+
+func TestAddTemplateValidatesReferences(t *testing.T) {
+	fdb := newFakeDB()
+	fdb.AddAccident(&db.Accident{Name: "leak"})
+	fdb.AddCustomProgram("ignite", "", nil)
+	h := NewTemplateHandler(fdb)
+
+	for _, tc := range []struct {
+		name, body string
+		want       int
+	}{
+		{"valid", `{"name":"orbit","duration":30,"accidents":["leak"],"programs":[1]}`, http.StatusOK},
+		{"missing duration", `{"name":"orbit"}`, http.StatusBadRequest},
+		{"unknown accident", `{"name":"orbit","duration":30,"accidents":["meteor"]}`, http.StatusBadRequest},
+		{"unknown program", `{"name":"orbit","duration":30,"programs":[9]}`, http.StatusBadRequest},
+		{"unknown physics model", `{"name":"orbit","duration":30,"physics_model":"warp"}`, http.StatusBadRequest},
+		{"invalid success rate", `{"name":"orbit","duration":30,"success_rate":120}`, http.StatusBadRequest},
+		{"invalid phase", `{"name":"orbit","duration":30,"status":{"Phase":"warp"}}`, http.StatusBadRequest},
+	} {
+		c, rec := newTestContext(http.MethodPost, "/api/v1/template", tc.body)
+		if err := h.AddTemplate(c); err != nil {
+			t.Fatalf("%s: AddTemplate returned error: %v", tc.name, err)
+		}
+		if rec.Code != tc.want {
+			t.Errorf("%s: status %d, want %d: %s", tc.name, rec.Code, tc.want, rec.Body)
+		}
+	}
+}
+
+func TestAddMissionFromTemplate(t *testing.T) {
+	fdb := newFakeDB()
+	fdb.AddAccident(&db.Accident{Name: "leak"})
+	fdb.AddMissionTemplate(&db.MissionTemplate{
+		Name: "orbit", Desc: "reach orbit", Duration: 30, SuccessRate: 80,
+		Accidents: []string{"leak"}, Setting: &db.RocketSetting{Thrust: 50},
+	})
+	h := NewMissionHandler(fdb, nil)
+
+	// 请求中设置的配置覆盖模板，没有设置的配置使用模板中的值
+	c, rec := newTestContext(http.MethodPost, "/api/v1/mission", `{"name":"m1","template_id":1,"success_rate":90}`)
+	c.Set("username", "commander")
+	if err := h.AddMission(c); err != nil {
+		t.Fatalf("AddMission returned error: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("AddMission status %d: %s", rec.Code, rec.Body)
+	}
+	m := fdb.mission(1)
+	if m.TemplateID != 1 || m.Duration != 30 || m.SuccessRate != 90 || m.Desc != "reach orbit" ||
+		len(m.Accidents) != 1 || m.InitialSetting == nil || m.InitialSetting.Thrust != 50 {
+		t.Errorf("mission from template: %+v", m)
+	}
+
+	c, rec = newTestContext(http.MethodPost, "/api/v1/mission", `{"name":"m2","template_id":9}`)
+	c.Set("username", "commander")
+	if err := h.AddMission(c); err != nil {
+		t.Fatalf("AddMission returned error: %v", err)
+	}
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("AddMission with unknown template: status %d, want 400", rec.Code)
+	}
+}
+
+func TestCloneMission(t *testing.T) {
+	src := &db.Mission{
+		Name: "src", CreatedBy: "alice", Status: db.MissionStatusCompleted, Duration: 20, SuccessRate: 70,
+		Accidents: []string{"leak"}, InitialSetting: &db.RocketSetting{Thrust: 40},
+		Result: &db.MissionResult{Status: db.MissionStatusCompleted},
+	}
+	src.ID = 1
+	fdb := newFakeDB(src)
+	h := NewMissionHandler(fdb, nil)
+
+	for _, tc := range []struct {
+		name, id, body string
+		want           int
+	}{
+		{"clone", "1", `{"name":"copy"}`, http.StatusOK},
+		{"duplicate name", "1", `{"name":"copy"}`, http.StatusConflict},
+		{"missing name", "1", `{}`, http.StatusBadRequest},
+		{"not found", "9", `{"name":"other"}`, http.StatusNotFound},
+	} {
+		c, rec := newTestContext(http.MethodPost, "/api/v1/mission/"+tc.id+"/clone", tc.body, "id", tc.id)
+		c.Set("username", "bob")
+		if err := h.CloneMission(c); err != nil {
+			t.Fatalf("%s: CloneMission returned error: %v", tc.name, err)
+		}
+		if rec.Code != tc.want {
+			t.Errorf("%s: status %d, want %d: %s", tc.name, rec.Code, tc.want, rec.Body)
+		}
+		if tc.name != "clone" {
+			continue
+		}
+		var resp ApiResp[db.Mission]
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		m := fdb.mission(resp.Data.ID)
+		if m.CreatedBy != "bob" || m.Status != db.MissionStatusPending || m.Result != nil || m.Duration != 20 ||
+			m.SuccessRate != 70 || len(m.Accidents) != 1 || m.InitialSetting.Thrust != 40 {
+			t.Errorf("cloned mission: %+v", m)
+		}
+		// 修改副本的配置不影响原任务
+		m.InitialSetting.Thrust = 10
+		if src.InitialSetting.Thrust != 40 {
+			t.Error("cloned mission shares initial setting with source")
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package db
 
 import (
+	"slices"
 	"time"
 
 	"github.com/jackc/pgx/pgtype"
@@ -14,6 +15,12 @@ type Iface interface {
 	EventIface
 	AccidentIface
 	DiagnosticIface
+	AlarmIface
+	PresetIface
+	ScenarioIface
+	InjectionAuditIface
+	MissionTemplateIface
+	TelemetryIface
 }
 
 type baseModel struct {
@@ -28,6 +35,12 @@ type MissionIface interface {
 	UpdateMissionStatus(id uint, status MissionStatus) error
 	GetMission(id uint) (*Mission, error)
 	GetMissionList() ([]*Mission, error)
+	// GetScheduledMissions 返回设置了开始时间或者已经开始、还没有结束的任务
+	GetScheduledMissions() ([]*Mission, error)
+	// StartMission 将任务标记为进行中，并记录实际开始时间
+	StartMission(id uint, at time.Time) error
+	// EndMission 将任务标记为 status，并记录实际结束时间和评估结果，result 可以为空
+	EndMission(id uint, status MissionStatus, result *MissionResult, at time.Time) error
 }
 
 type MissionOptFunc func(m *Mission)
@@ -40,6 +53,87 @@ func WithMissionDesc(desc string) MissionOptFunc {
 	return func(m *Mission) { m.Desc = desc }
 }
 
+func WithMissionPhysicsModel(name string) MissionOptFunc {
+	return func(m *Mission) { m.PhysicsModel = name }
+}
+
+func WithMissionHazard(cfg HazardConfig) MissionOptFunc {
+	return func(m *Mission) { m.Hazard = &cfg }
+}
+
+func WithMissionScenario(id uint) MissionOptFunc {
+	return func(m *Mission) { m.ScenarioID = id }
+}
+
+// WithMissionStartTime 设置任务的开始时间，调度器会在开始时间启动任务
+func WithMissionStartTime(t time.Time) MissionOptFunc {
+	return func(m *Mission) { m.StartTime = t }
+}
+
+func WithMissionCriteria(c SuccessCriteria) MissionOptFunc {
+	return func(m *Mission) { m.Criteria = &c }
+}
+
+func WithMissionAccidents(names []string) MissionOptFunc {
+	return func(m *Mission) { m.Accidents = slices.Clone(names) }
+}
+
+func WithMissionPrograms(ids []uint) MissionOptFunc {
+	return func(m *Mission) { m.Programs = slices.Clone(ids) }
+}
+
+func WithMissionInitialSetting(setting RocketSetting) MissionOptFunc {
+	return func(m *Mission) { m.InitialSetting = &setting }
+}
+
+func WithMissionInitialStatus(status RocketStatus) MissionOptFunc {
+	return func(m *Mission) { m.InitialStatus = &status }
+}
+
+// WithMissionTemplate 使用模板中的配置，放在其他选项之前时其他选项可以覆盖模板
+func WithMissionTemplate(t *MissionTemplate) MissionOptFunc {
+	return func(m *Mission) {
+		m.TemplateID = t.ID
+		m.Desc = t.Desc
+		if t.SuccessRate > 0 {
+			m.SuccessRate = t.SuccessRate
+		}
+		m.PhysicsModel = t.PhysicsModel
+		m.Hazard = clonePtr(t.Hazard)
+		m.ScenarioID = t.ScenarioID
+		m.Accidents = slices.Clone(t.Accidents)
+		m.Programs = slices.Clone(t.Programs)
+		m.InitialSetting = clonePtr(t.Setting)
+		m.InitialStatus = clonePtr(t.Status)
+		m.Criteria = t.Criteria.Clone()
+	}
+}
+
+// WithMissionCloneOf 复制 src 的配置，不复制运行状态
+func WithMissionCloneOf(src *Mission) MissionOptFunc {
+	return func(m *Mission) {
+		m.TemplateID = src.TemplateID
+		m.Desc = src.Desc
+		m.SuccessRate = src.SuccessRate
+		m.PhysicsModel = src.PhysicsModel
+		m.Hazard = clonePtr(src.Hazard)
+		m.ScenarioID = src.ScenarioID
+		m.Accidents = slices.Clone(src.Accidents)
+		m.Programs = slices.Clone(src.Programs)
+		m.InitialSetting = clonePtr(src.InitialSetting)
+		m.InitialStatus = clonePtr(src.InitialStatus)
+		m.Criteria = src.Criteria.Clone()
+	}
+}
+
+func clonePtr[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
+}
+
 type MissionStatus int
 
 const (
@@ -48,25 +142,172 @@ const (
 	MissionStatusCompleted
 	MissionStatusFailed
 	MissionStatusCancelled
+	MissionStatusPaused
 )
 
+// Ended 返回任务是否已经结束
+func (s MissionStatus) Ended() bool {
+	return s == MissionStatusCompleted || s == MissionStatusFailed || s == MissionStatusCancelled
+}
+
 const DefaultSuccessRate float64 = 98.0
 
+// HazardConfig 描述事故率如何随火箭当前的设置和状态变化，各项为 0 表示不受该项影响
+type HazardConfig struct {
+	Stabilizer  float64 `json:"stabilizer"`  // 稳定器的影响，稳定器从 50 调到 100（或 0）时事故率减少（或增加）Stabilizer/2 倍
+	Thrust      float64 `json:"thrust"`      // 推力的影响，推力从 50 增加到 100 时事故率增加 Thrust 倍
+	Temperature float64 `json:"temperature"` // 温度的影响，温度从 20 升到告警阈值时事故率增加 Temperature 倍
+}
+
+// DefaultHazardConfig 是任务没有配置 Hazard 时使用的默认值
+var DefaultHazardConfig = HazardConfig{Stabilizer: 1, Thrust: 1, Temperature: 1}
+
 // Mission 表示用户创建的一个任务
 type Mission struct {
 	baseModel
 
-	Name        string        `gorm:"unique,type:text"` // 任务名称
-	Desc        string        `gorm:"type:text"`        // 任务描述
-	Status      MissionStatus `gorm:"type:int"`         // 任务状态
-	StartTime   time.Time     `gorm:"type:timestamptz"` // 任务开始时间
-	EndTime     time.Time     `gorm:"type:timestamptz"` // 任务结束时间
-	Duration    int           `gorm:"type:int"`         // 预估任务持续事件（分钟）
-	SuccessRate float64       `gorm:"type:float"`       // 任务成功率
-	CreatedBy   string        `gorm:"type:text"`        // 创建者
+	Name         string        `gorm:"unique,type:text"` // 任务名称
+	Desc         string        `gorm:"type:text"`        // 任务描述
+	Status       MissionStatus `gorm:"type:int"`         // 任务状态
+	StartTime    time.Time     `gorm:"type:timestamptz"` // 任务开始时间，开始前为计划时间，为零值表示不自动调度
+	EndTime      time.Time     `gorm:"type:timestamptz"` // 任务实际结束时间
+	Duration     int           `gorm:"type:int"`         // 预估任务持续事件（分钟）
+	SuccessRate  float64       `gorm:"type:float"`       // 任务成功率（0-100），即整个任务期间不发生随机事故的概率
+	PhysicsModel string        `gorm:"type:text"`        // 物理模型名称，为空时使用默认模型
+	CreatedBy    string        `gorm:"type:text"`        // 创建者
+
+	Hazard     *HazardConfig `gorm:"type:jsonb;serializer:json"` // 事故率的调节方式，为空时使用 DefaultHazardConfig
+	ScenarioID uint          `gorm:"index"`                      // 训练剧本，为 0 表示没有剧本
+
+	TemplateID     uint           `gorm:"index"`                      // 创建任务时使用的模板，为 0 表示没有模板
+	Accidents      []string       `gorm:"type:jsonb;serializer:json"` // 可能随机发生的事故名称，为空表示整个事故目录
+	Programs       []uint         `gorm:"type:jsonb;serializer:json"` // 可以执行的自定义程序，为空表示不限制，系统程序始终可以执行
+	InitialSetting *RocketSetting `gorm:"type:jsonb;serializer:json"` // 初始设置，为空时使用 DefaultRocketSetting
+	InitialStatus  *RocketStatus  `gorm:"type:jsonb;serializer:json"` // 初始状态，为空时使用 DefaultRocketStatus
+
+	Criteria *SuccessCriteria `gorm:"type:jsonb;serializer:json"` // 任务结束时的评估标准，为空时只要没有中止或损毁就算成功
+	Result   *MissionResult   `gorm:"type:jsonb;serializer:json"` // 任务结束时的评估结果
+}
+
+type CriterionKind string
+
+const (
+	CriterionKindReachPhase     CriterionKind = "reach_phase"      // 任务期间到达过 Phase，例如入轨
+	CriterionKindFinal          CriterionKind = "final"            // 结束时 Field Op Value 成立，例如 HullLevel > 50
+	CriterionKindMin            CriterionKind = "min"              // 任务期间 Field 的最小值 Op Value 成立，例如 OxygenLevel 的最小值 >= 10
+	CriterionKindMax            CriterionKind = "max"              // 任务期间 Field 的最大值 Op Value 成立
+	CriterionKindNoActiveAlarms CriterionKind = "no_active_alarms" // 结束时没有未清除的告警
+)
+
+// SuccessCriterion 是一项成功标准，Field 为 RocketStatus 或 RocketSetting 的字段名，min、max 只支持 RocketStatus 的数值字段
+type SuccessCriterion struct {
+	Kind   CriterionKind `json:"kind"`
+	Field  string        `json:"field,omitempty"`
+	Op     ConditionOp   `json:"op,omitempty"`
+	Value  float64       `json:"value,omitempty"`
+	Phase  FlightPhase   `json:"phase,omitempty"`
+	Points float64       `json:"points"` // 分值，为 0 时为 1
+	Desc   string        `json:"desc,omitempty"`
+}
+
+// DefaultResponseTarget 是确认告警的默认目标时间（秒）
+const DefaultResponseTarget float64 = 30
+
+// SuccessCriteria 是任务的评估标准，所有标准都满足时任务成功
+type SuccessCriteria struct {
+	Criteria       []SuccessCriterion `json:"criteria"`
+	ResponseTarget float64            `json:"response_target"` // 确认系统告警的目标时间（秒），为 0 时使用 DefaultResponseTarget
+	ResponseWeight float64            `json:"response_weight"` // 操作员响应得分在总分中的占比（0-1），为 0 时不计入总分
+}
+
+// Clone 返回 c 的副本，c 为空时返回空
+func (c *SuccessCriteria) Clone() *SuccessCriteria {
+	if c == nil {
+		return nil
+	}
+	clone := *c
+	clone.Criteria = slices.Clone(c.Criteria)
+	return &clone
+}
+
+// MissionResult 是任务结束时的评估结果，得分均为 0-100
+type MissionResult struct {
+	Status        MissionStatus     `json:"status"` // Completed 或 Failed
+	Reason        string            `json:"reason,omitempty"`
+	Score         float64           `json:"score"`
+	CriteriaScore float64           `json:"criteria_score"`
+	ResponseScore float64           `json:"response_score"` // 没有系统告警时为 100
+	Criteria      []CriterionResult `json:"criteria"`
+	Operators     []OperatorResult  `json:"operators"`
+	EvaluatedAt   time.Time         `json:"evaluated_at"`
+}
+
+type CriterionResult struct {
+	SuccessCriterion
+	Actual float64 `json:"actual"` // 实际值，reach_phase 为 1 或 0，no_active_alarms 为未清除的告警数量
+	Passed bool    `json:"passed"`
+	Score  float64 `json:"score"`
+}
+
+// OperatorResult 是一个操作员确认系统告警的响应情况
+type OperatorResult struct {
+	Operator    string  `json:"operator"`
+	Alarms      int     `json:"alarms"`       // 确认的告警数量
+	AvgResponse float64 `json:"avg_response"` // 平均确认时间（秒）
+	MaxResponse float64 `json:"max_response"` // 最长确认时间（秒）
+	Score       float64 `json:"score"`
+}
+
+// InitialState 返回任务的初始设置和状态
+func (m *Mission) InitialState() (RocketSetting, RocketStatus) {
+	setting, status := DefaultRocketSetting, DefaultRocketStatus
+	if m.InitialSetting != nil {
+		setting = *m.InitialSetting
+	}
+	if m.InitialStatus != nil {
+		status = *m.InitialStatus
+	}
+	return setting, status
+}
+
+// AllowsAccident 返回事故是否可能在任务中随机发生
+func (m *Mission) AllowsAccident(name string) bool {
+	return len(m.Accidents) == 0 || slices.Contains(m.Accidents, name)
+}
+
+// AllowsProgram 返回自定义程序是否可以在任务中执行
+func (m *Mission) AllowsProgram(p *CustomProgram) bool {
+	return p.IsSystem || len(m.Programs) == 0 || slices.Contains(m.Programs, p.ID)
+}
+
+type MissionTemplateIface interface {
+	AddMissionTemplate(t *MissionTemplate) error
+	GetMissionTemplate(id uint) (*MissionTemplate, error)
+	GetMissionTemplateList() ([]*MissionTemplate, error)
+	UpdateMissionTemplate(t *MissionTemplate) error
+	DeleteMissionTemplate(id uint) error
+}
+
+// MissionTemplate 是可以重复使用的任务配置，创建任务时通过 template_id 引用
+type MissionTemplate struct {
+	baseModel
+	Name         string         `gorm:"unique,type:text" json:"name"`
+	Desc         string         `gorm:"type:text" json:"desc"`
+	Duration     int            `gorm:"type:int" json:"duration"`
+	SuccessRate  float64        `gorm:"type:float" json:"success_rate"` // 为 0 时使用 DefaultSuccessRate
+	PhysicsModel string         `gorm:"type:text" json:"physics_model"`
+	Hazard       *HazardConfig  `gorm:"type:jsonb;serializer:json" json:"hazard"`
+	ScenarioID   uint           `json:"scenario_id"`
+	Accidents    []string       `gorm:"type:jsonb;serializer:json" json:"accidents"`
+	Programs     []uint         `gorm:"type:jsonb;serializer:json" json:"programs"`
+	Setting      *RocketSetting `gorm:"type:jsonb;serializer:json" json:"setting"`
+	Status       *RocketStatus  `gorm:"type:jsonb;serializer:json" json:"status"`
+
+	Criteria *SuccessCriteria `gorm:"type:jsonb;serializer:json" json:"criteria"`
 }
 
 type SystemStateIface interface {
+	AddSystemState(missionID uint, setting RocketSetting, status RocketStatus) (*SystemState, error)
 	GetSystemState(missionID uint) (*SystemState, error)
 	UpdateSystemSetting(missionID uint, setting RocketSetting) (err error)
 	UpdateSystemStatus(missionID uint, status RocketStatus) (err error)
@@ -99,25 +340,144 @@ type RocketSetting struct {
 	Pressure   float64 `gorm:"type:float"` // 压力水平
 }
 
+// FlightPhase 表示火箭当前所处的飞行阶段
+type FlightPhase string
+
+const (
+	FlightPhasePreLaunch FlightPhase = "pre_launch" // 发射前
+	FlightPhaseCountdown FlightPhase = "countdown"  // 倒计时
+	FlightPhaseAscent    FlightPhase = "ascent"     // 上升
+	FlightPhaseOrbit     FlightPhase = "orbit"      // 入轨
+	FlightPhaseDescent   FlightPhase = "descent"    // 下降
+	FlightPhaseLanded    FlightPhase = "landed"     // 着陆
+	FlightPhaseAborted   FlightPhase = "aborted"    // 中止
+)
+
 type RocketStatus struct {
-	Launched bool `gorm:"type:bool"` // 是否发射
+	Launched bool        `gorm:"type:bool"` // 是否发射
+	Phase    FlightPhase `gorm:"type:text"` // 飞行阶段
 
 	HullLevel        float64 `gorm:"type:float"` // 船体完整性
 	FuelLevel        float64 `gorm:"type:float"`
 	OxygenLevel      float64 `gorm:"type:float"`
 	TemperatureLevel float64 `gorm:"type:float"`
 	PressureLevel    float64 `gorm:"type:float"`
+	AltitudeLevel    float64 `gorm:"type:float"` // 实际高度（km）
+	VelocityLevel    float64 `gorm:"type:float"` // 实际垂直速度（m/s）
+
+	ElapsedTime float64 `gorm:"type:float"` // 任务经过时间（秒），从点火升空开始按模拟时间计算
+	MissionTime float64 `gorm:"type:float"` // 任务开始后经过的模拟时间（秒），暂停期间不增加，任务到达 Duration 时结束
+	SimElapsed  float64 `gorm:"type:float"` // 任务第一次加载后经过的模拟时间（秒），不论任务状态，暂停期间不增加，用于计算告警响应时间
+
+	Extremes StatusExtremes `gorm:"type:jsonb;serializer:json"` // 任务期间各项状态的范围，用于任务评估
+}
+
+// StatusRange 是一项状态在任务期间的最小值和最大值
+type StatusRange struct {
+	Min float64
+	Max float64
+}
+
+func (r *StatusRange) track(v float64) {
+	r.Min, r.Max = min(r.Min, v), max(r.Max, v)
+}
+
+// StatusExtremes 记录 RocketStatus 各项数值的范围，Valid 为假表示还没有开始记录
+type StatusExtremes struct {
+	Valid            bool
+	HullLevel        StatusRange
+	FuelLevel        StatusRange
+	OxygenLevel      StatusRange
+	TemperatureLevel StatusRange
+	PressureLevel    StatusRange
+	AltitudeLevel    StatusRange
+	VelocityLevel    StatusRange
+}
+
+// Range 返回字段的范围，字段名与 RocketStatus 一致
+func (e *StatusExtremes) Range(field string) (StatusRange, bool) {
+	switch field {
+	case "HullLevel":
+		return e.HullLevel, true
+	case "FuelLevel":
+		return e.FuelLevel, true
+	case "OxygenLevel":
+		return e.OxygenLevel, true
+	case "TemperatureLevel":
+		return e.TemperatureLevel, true
+	case "PressureLevel":
+		return e.PressureLevel, true
+	case "AltitudeLevel":
+		return e.AltitudeLevel, true
+	case "VelocityLevel":
+		return e.VelocityLevel, true
+	}
+	return StatusRange{}, false
+}
+
+// TrackExtremes 用当前的数值更新 Extremes
+func (s *RocketStatus) TrackExtremes() {
+	e := &s.Extremes
+	for _, f := range []struct {
+		r *StatusRange
+		v float64
+	}{
+		{&e.HullLevel, s.HullLevel}, {&e.FuelLevel, s.FuelLevel}, {&e.OxygenLevel, s.OxygenLevel},
+		{&e.TemperatureLevel, s.TemperatureLevel}, {&e.PressureLevel, s.PressureLevel},
+		{&e.AltitudeLevel, s.AltitudeLevel}, {&e.VelocityLevel, s.VelocityLevel},
+	} {
+		if e.Valid {
+			f.r.track(f.v)
+		} else {
+			*f.r = StatusRange{Min: f.v, Max: f.v}
+		}
+	}
+	e.Valid = true
+}
+
+// 新任务没有 SystemState 时使用的初始设置与状态
+var (
+	DefaultRocketSetting = RocketSetting{
+		Power:      true,
+		Comms:      true,
+		Nav:        true,
+		Life:       true,
+		Stabilizer: 50,
+		Oxygen:     50,
+		PowerLevel: 50,
+		Pressure:   50,
+	}
+	DefaultRocketStatus = RocketStatus{
+		Phase:            FlightPhasePreLaunch,
+		HullLevel:        100,
+		FuelLevel:        100,
+		OxygenLevel:      100,
+		TemperatureLevel: 20,
+		PressureLevel:    50,
+	}
+)
+
+type PresetIface interface {
+	AddPreset(name, desc string, setting RocketSetting) (*SystemPreset, error)
+	GetPreset(id uint) (*SystemPreset, error)
+	GetPresetList() ([]*SystemPreset, error)
+	UpdatePreset(id uint, name, desc string, setting RocketSetting) error
+	DeletePreset(id uint) error
 }
 
 type SystemPreset struct {
 	baseModel
-	Name string `gorm:"unique,type:text"` // 预设名称
-	Desc string `gorm:"type:text"`        // 预设描述
-	RocketSetting
+	Name          string `gorm:"unique,type:text" json:"name"` // 预设名称
+	Desc          string `gorm:"type:text" json:"desc"`        // 预设描述
+	RocketSetting `json:"setting"`
 }
 
 type CustomProgramIface interface {
-	GetCusomProgram(id uint) (ProgramSteps, error)
+	AddCustomProgram(name, desc string, steps ProgramSteps) (*CustomProgram, error)
+	GetCustomProgram(id uint) (*CustomProgram, error)
+	GetCustomProgramList() ([]*CustomProgram, error)
+	UpdateCustomProgram(id uint, name, desc string, steps ProgramSteps) error
+	DeleteCustomProgram(id uint) error
 }
 
 // 自定义火箭程序的单步操作
@@ -126,22 +486,65 @@ type ProgramStep struct {
 	Value     string    `gorm:"type:text" json:"value"`      // 事件值
 	Desc      string    `gorm:"type:text" json:"desc"`       // 事件描述
 	Duration  int       `gorm:"type:int" json:"duration"`    // 事件持续时间
+
+	// 以下字段只用于控制流步骤（wait_until、repeat、if），普通步骤不需要设置
+	Condition *StepCondition `json:"condition,omitempty"` // wait_until、if 的条件
+	Timeout   int            `json:"timeout,omitempty"`   // wait_until 的超时时间（毫秒），0 表示一直等待
+	Times     int            `json:"times,omitempty"`     // repeat 的次数
+	Steps     ProgramSteps   `json:"steps,omitempty"`     // repeat 的循环体，if 条件成立时执行的步骤
+	Else      ProgramSteps   `json:"else,omitempty"`      // if 条件不成立时执行的步骤
 }
 
 type ProgramSteps []ProgramStep
 
+// StepCondition 是对当前 RocketStatus 或 RocketSetting 的比较，例如 FuelLevel < 30
+type StepCondition struct {
+	Field string      `json:"field"` // RocketStatus 或 RocketSetting 的字段名
+	Op    ConditionOp `json:"op"`
+	Value float64     `json:"value"`
+}
+
+type ConditionOp string
+
+const (
+	ConditionOpLt ConditionOp = "<"
+	ConditionOpLe ConditionOp = "<="
+	ConditionOpGt ConditionOp = ">"
+	ConditionOpGe ConditionOp = ">="
+	ConditionOpEq ConditionOp = "=="
+	ConditionOpNe ConditionOp = "!="
+)
+
 type CustomProgram struct {
 	baseModel
-	IsSystem bool         `gorm:"type:bool"`                        // 是否是系统预设
-	Name     string       `gorm:"unique,type:text"`                 // 程序名称
-	Desc     string       `gorm:"type:text"`                        // 程序描述
-	Steps    pgtype.JSONB `gorm:"type:jsonb;default:'[]';not null"` // 程序步骤
+	IsSystem bool         `gorm:"type:bool" json:"is_system"`                // 是否是系统预设
+	Name     string       `gorm:"unique,type:text" json:"name"`              // 程序名称
+	Desc     string       `gorm:"type:text" json:"desc"`                     // 程序描述
+	Steps    pgtype.JSONB `gorm:"type:jsonb;default:'[]';not null" json:"-"` // 程序步骤
+}
+
+// ProgramSteps 解析程序步骤
+func (p *CustomProgram) ProgramSteps() (ProgramSteps, error) {
+	var steps ProgramSteps
+	if err := p.Steps.AssignTo(&steps); err != nil {
+		return nil, err
+	}
+	return steps, nil
 }
 
 type EventIface interface {
 	AddEvent(missionID uint, eventType EventType, value string, createdBy string) (*Event, error)
+	// AddInjectedEvent 记录教员注入的事件，事件由 system 发起
+	AddInjectedEvent(missionID uint, eventType EventType, value string) (*Event, error)
+	// AddSubEvent 记录子事件，注入事件的子事件同样标记为注入
 	AddSubEvent(missionID, parentID uint, eventType EventType, value string, createdBy string) (*Event, error)
 	UpdateEventStatus(id uint, status EventStatus) error
+	UpdateEventDesc(id uint, desc string) error
+	// GetRecentEvents 用于加入任务时回放，不包含注入的事件
+	GetRecentEvents(missionID uint, limit int) ([]*Event, error)
+	GetEventList(missionID uint, filter EventFilter) ([]*Event, error)
+	GetEvent(id uint) (*Event, error)
+	GetSubEvents(parentIDs []uint) ([]*Event, error)
 }
 
 type EventType string
@@ -149,13 +552,22 @@ type EventType string
 const (
 	EventTypeErr EventType = "error"
 
-	EventTypeJoin  EventType = "join"
-	EventTypeLeave EventType = "leave"
+	EventTypeJoin     EventType = "join"
+	EventTypeLeave    EventType = "leave"
+	EventTypeSnapshot EventType = "snapshot" // 加入任务时发送的完整状态，只由服务端发送
 
 	EventTypeLanuch EventType = "launch"
 	EventTypeAbort  EventType = "abort"
 	EventTypeLand   EventType = "land"
 	EventTypeTest   EventType = "test"
+	EventTypePhase  EventType = "phase" // 飞行阶段变化，由系统产生
+
+	EventTypeMissionWarning EventType = "mission_warning" // 任务即将结束，Value 为剩余秒数，由调度器产生
+	EventTypeMissionEnd     EventType = "mission_end"     // 任务结束，Value 为任务的最终状态
+
+	EventTypeSimSpeed EventType = "sim_speed" // 设置模拟速度，Value 为倍率
+	EventTypePause    EventType = "pause"
+	EventTypeResume   EventType = "resume"
 
 	EventTypeAccident EventType = "accident"
 
@@ -163,11 +575,19 @@ const (
 	EventTypeDiagnoseResult EventType = "diagnose_result"
 
 	EventTypeAlarmSet   EventType = "set_alarm"
+	EventTypeAlarmAck   EventType = "ack_alarm"
 	EventTypeAlarmClear EventType = "clear_alarm"
 
+	EventTypeApplyPreset EventType = "apply_preset" // 应用预设，Value 为预设 ID
+
 	EventTypeCustomAdd   EventType = "custom_add"
 	EventTypeCusomCancel EventType = "custom_cancel"
 
+	// 自定义程序中的控制流步骤，只作为子事件出现
+	EventTypeWaitUntil EventType = "wait_until"
+	EventTypeRepeat    EventType = "repeat"
+	EventTypeIf        EventType = "if"
+
 	// 通过影响火箭设置来影响火箭状态的事件
 	// 布尔值
 	EventTypeTriggerPower EventType = "power"
@@ -192,6 +612,8 @@ const (
 	EventTypeOxygenChange   EventType = "oxygen_change"
 	EventTypeTempChange     EventType = "temperature_change"
 	EventTypePressureChange EventType = "pressure_change"
+	EventTypeAltitudeChange EventType = "altitude_change"
+	EventTypeVelocityChange EventType = "velocity_change"
 )
 
 type EventStatus int
@@ -206,13 +628,32 @@ const (
 
 type Event struct {
 	baseModel
-	MissionID uint        `gorm:"index"`
-	CreatedBy string      `gorm:"type:text"` // 创建者
-	Desc      string      `gorm:"type:text"` // 事件描述
-	PartOf    uint        `gorm:"index"`     // 父事件
-	Status    EventStatus `gorm:"type:int"`  // 事件状态
-	Type      EventType   `gorm:"type:text"`
-	Value     string      `gorm:"type:text"`
+	MissionID uint        `gorm:"index" json:"mission_id"`
+	CreatedBy string      `gorm:"type:text" json:"created_by"` // 创建者
+	Desc      string      `gorm:"type:text" json:"desc"`       // 事件描述
+	PartOf    uint        `gorm:"index" json:"part_of"`        // 父事件
+	Status    EventStatus `gorm:"type:int" json:"status"`      // 事件状态
+	Type      EventType   `gorm:"type:text" json:"type"`
+	Value     string      `gorm:"type:text" json:"value"`
+
+	StartedAt  *time.Time `gorm:"type:timestamptz" json:"started_at"`  // 开始执行时间
+	FinishedAt *time.Time `gorm:"type:timestamptz" json:"finished_at"` // 完成、失败或取消的时间
+
+	Injected bool `gorm:"index;not null;default:false" json:"injected"` // 教员注入的事件及其子事件，只有教员可以查询
+}
+
+// EventFilter 是查询事件历史的条件，零值字段表示不过滤
+type EventFilter struct {
+	Types     []EventType
+	Status    *EventStatus
+	CreatedBy string
+	Since     time.Time // 包含
+	Until     time.Time // 不包含
+	PartOf    *uint     // 父事件 ID，0 表示只查询顶层事件
+	Cursor    uint      // 只返回 ID 大于 Cursor 的事件
+	Limit     int
+
+	IncludeInjected bool // 是否包含教员注入的事件
 }
 
 type DiagnosticStatus int
@@ -241,14 +682,177 @@ type DiagnosticIface interface {
 }
 
 type AccidentIface interface {
-	GetRandomAccident() (ProgramSteps, error)
+	AddAccident(a *Accident) error
+	GetAccident(id uint) (*Accident, error)
+	GetAccidentByName(name string) (*Accident, error)
+	GetAccidentList() ([]*Accident, error)
+	UpdateAccident(a *Accident) error
+	DeleteAccident(id uint) error
+}
+
+type ScenarioIface interface {
+	AddScenario(sc *Scenario) error
+	GetScenario(id uint) (*Scenario, error)
+	GetScenarioList() ([]*Scenario, error)
+	UpdateScenario(sc *Scenario) error
+	DeleteScenario(id uint) error
 }
 
+// Scenario 是可重复的训练剧本，在固定的任务经过时间注入事故和外部事件
+type Scenario struct {
+	baseModel
+	Name                   string          `gorm:"unique,type:text" json:"name"`
+	Desc                   string          `gorm:"type:text" json:"desc"`
+	DisableRandomAccidents bool            `gorm:"type:bool" json:"disable_random_accidents"` // 为真时只发生剧本中的事故
+	Events                 []ScenarioEvent `gorm:"type:jsonb;serializer:json" json:"events"`
+}
+
+// ScenarioEvent 是剧本中的一个事件，Accident 和 EventType 二选一
+type ScenarioEvent struct {
+	At        int       `json:"at"`                   // 任务经过时间（秒），即 T+At
+	Accident  string    `json:"accident,omitempty"`   // 注入的事故名称
+	EventType EventType `json:"event_type,omitempty"` // 注入的外部事件
+	Value     string    `json:"value,omitempty"`
+	Desc      string    `json:"desc,omitempty"`
+}
+
+type AccidentSeverity int
+
+const (
+	AccidentSeverityMinor    AccidentSeverity = iota // 轻微，不影响任务
+	AccidentSeverityMajor                            // 严重，需要操作员处理
+	AccidentSeverityCritical                         // 致命，可能导致任务失败
+)
+
 type Accident struct {
 	baseModel
-	Name  string       `gorm:"unique,type:text"`                 // 事故名称
-	Desc  string       `gorm:"type:text"`                        // 事故描述
-	Steps pgtype.JSONB `gorm:"type:jsonb;default:'[]';not null"` // 事故内容
+	Name     string           `gorm:"unique,type:text" json:"name"`              // 事故名称
+	Desc     string           `gorm:"type:text" json:"desc"`                     // 事故描述
+	Weight   float64          `gorm:"type:float;default:1" json:"weight"`        // 随机抽取的权重
+	Severity AccidentSeverity `gorm:"type:int;default:0" json:"severity"`        // 严重程度
+	Phases   []FlightPhase    `gorm:"type:jsonb;serializer:json" json:"phases"`  // 可能发生的飞行阶段，为空表示任意阶段
+	Steps    pgtype.JSONB     `gorm:"type:jsonb;default:'[]';not null" json:"-"` // 事故内容
+}
+
+// ProgramSteps 解析事故内容
+func (a *Accident) ProgramSteps() (ProgramSteps, error) {
+	var steps ProgramSteps
+	if err := a.Steps.AssignTo(&steps); err != nil {
+		return nil, err
+	}
+	return steps, nil
+}
+
+// SetProgramSteps 设置事故内容
+func (a *Accident) SetProgramSteps(steps ProgramSteps) error { return a.Steps.Set(steps) }
+
+// InPhase 返回事故是否可能在 phase 阶段发生
+func (a *Accident) InPhase(phase FlightPhase) bool {
+	return len(a.Phases) == 0 || slices.Contains(a.Phases, phase)
+}
+
+type AlarmLevel int
+
+const (
+	AlarmLevelWarning AlarmLevel = iota
+	AlarmLevelCritical
+)
+
+type AlarmStatus int
+
+const (
+	AlarmStatusActive       AlarmStatus = iota // 已触发，等待确认
+	AlarmStatusAcknowledged                    // 已确认，等待清除
+	AlarmStatusCleared                         // 已清除
+)
+
+// Alarm 表示任务中触发的一个告警，由阈值自动触发或由操作员手动触发
+type Alarm struct {
+	baseModel
+	MissionID      uint        `gorm:"index" json:"mission_id"`
+	Code           string      `gorm:"type:text" json:"code"` // 告警代码，例如 hull_low
+	Level          AlarmLevel  `gorm:"type:int" json:"level"`
+	Status         AlarmStatus `gorm:"type:int;index" json:"status"`
+	Desc           string      `gorm:"type:text" json:"desc"`
+	RaisedBy       string      `gorm:"type:text" json:"raised_by"`
+	AcknowledgedBy string      `gorm:"type:text" json:"acknowledged_by"`
+	AcknowledgedAt *time.Time  `gorm:"type:timestamptz" json:"acknowledged_at"`
+	ClearedBy      string      `gorm:"type:text" json:"cleared_by"`
+	ClearedAt      *time.Time  `gorm:"type:timestamptz" json:"cleared_at"`
+	// 触发和首次确认（或直接清除）时的 RocketStatus.SimElapsed（秒），用于计算响应时间
+	RaisedSimTime    float64  `gorm:"type:float" json:"raised_sim_time"`
+	RespondedSimTime *float64 `gorm:"type:float" json:"responded_sim_time"`
+}
+
+type AlarmIface interface {
+	// simTime 为操作发生时的 RocketStatus.SimElapsed（秒）
+	AddAlarm(missionID uint, code string, level AlarmLevel, desc, raisedBy string, simTime float64) (*Alarm, error)
+	GetAlarm(id uint) (*Alarm, error)
+	GetAlarmList(missionID uint, activeOnly bool) ([]*Alarm, error)
+	AcknowledgeAlarm(id uint, user string, simTime float64) error
+	ClearAlarm(id uint, user string, simTime float64) error
+}
+
+type InjectionKind string
+
+const (
+	InjectionKindAccident InjectionKind = "accident" // 注入事故目录中的事故
+	InjectionKindStatus   InjectionKind = "status"   // 直接改变火箭状态
+)
+
+// InjectionAudit 记录教员向任务注入的故障，只有教员可以查看
+type InjectionAudit struct {
+	baseModel
+	MissionID  uint          `gorm:"index" json:"mission_id"`
+	Instructor string        `gorm:"type:text" json:"instructor"`
+	Kind       InjectionKind `gorm:"type:text" json:"kind"`
+	Target     string        `gorm:"type:text" json:"target"` // 事故名称或事件类型
+	Value      string        `gorm:"type:text" json:"value"`
+	Reason     string        `gorm:"type:text" json:"reason"`
+	EventID    uint          `json:"event_id"` // 注入产生的事件，失败时为 0
+	Result     string        `gorm:"type:text" json:"result"`
+}
+
+type InjectionAuditIface interface {
+	AddInjectionAudit(a *InjectionAudit) error
+	// GetInjectionAuditList 返回任务的注入记录，missionID 为 0 时返回所有任务的记录
+	GetInjectionAuditList(missionID uint) ([]*InjectionAudit, error)
+}
+
+// TelemetrySample 是飞行中按固定模拟时间间隔记录的火箭状态，用于任务复盘中的资源曲线
+type TelemetrySample struct {
+	baseModel
+	MissionID        uint        `gorm:"index" json:"mission_id"`
+	ElapsedTime      float64     `gorm:"type:float" json:"elapsed_time"` // 任务经过时间（秒）
+	Phase            FlightPhase `gorm:"type:text" json:"phase"`
+	HullLevel        float64     `gorm:"type:float" json:"hull_level"`
+	FuelLevel        float64     `gorm:"type:float" json:"fuel_level"`
+	OxygenLevel      float64     `gorm:"type:float" json:"oxygen_level"`
+	TemperatureLevel float64     `gorm:"type:float" json:"temperature_level"`
+	PressureLevel    float64     `gorm:"type:float" json:"pressure_level"`
+	AltitudeLevel    float64     `gorm:"type:float" json:"altitude_level"`
+	VelocityLevel    float64     `gorm:"type:float" json:"velocity_level"`
+}
+
+func NewTelemetrySample(missionID uint, status RocketStatus) *TelemetrySample {
+	return &TelemetrySample{
+		MissionID:        missionID,
+		ElapsedTime:      status.ElapsedTime,
+		Phase:            status.Phase,
+		HullLevel:        status.HullLevel,
+		FuelLevel:        status.FuelLevel,
+		OxygenLevel:      status.OxygenLevel,
+		TemperatureLevel: status.TemperatureLevel,
+		PressureLevel:    status.PressureLevel,
+		AltitudeLevel:    status.AltitudeLevel,
+		VelocityLevel:    status.VelocityLevel,
+	}
+}
+
+type TelemetryIface interface {
+	AddTelemetrySample(missionID uint, status RocketStatus) error
+	// GetTelemetrySamples 按经过时间升序返回任务的所有采样
+	GetTelemetrySamples(missionID uint) ([]*TelemetrySample, error)
 }
 
 // --- 实现结构体声明 ---
@@ -258,3 +862,9 @@ type CustomProgramService struct{ *gorm.DB }
 type EventService struct{ *gorm.DB }
 type AccidentService struct{ *gorm.DB }
 type DiagnosticService struct{ *gorm.DB }
+type AlarmService struct{ *gorm.DB }
+type PresetService struct{ *gorm.DB }
+type ScenarioService struct{ *gorm.DB }
+type InjectionAuditService struct{ *gorm.DB }
+type MissionTemplateService struct{ *gorm.DB }
+type TelemetryService struct{ *gorm.DB }
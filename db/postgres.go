@@ -1,9 +1,12 @@
 package db
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
+	"reflect"
+	"slices"
 	"time"
 
 	"github.com/eli-yip/rocket-control/config"
@@ -47,10 +50,11 @@ func NewPostgresDB(c config.DatabaseConfig) (db *gorm.DB, err error) {
 // --- MissionIface 实现 ---
 func (s *MissionService) AddMission(name, user string, duration int, opts ...MissionOptFunc) (*Mission, error) {
 	m := &Mission{
-		Name:      name,
-		CreatedBy: user,
-		Duration:  duration,
-		Status:    MissionStatusPending,
+		Name:        name,
+		CreatedBy:   user,
+		Duration:    duration,
+		Status:      MissionStatusPending,
+		SuccessRate: DefaultSuccessRate,
 	}
 	for _, opt := range opts {
 		opt(m)
@@ -81,7 +85,74 @@ func (s *MissionService) GetMissionList() ([]*Mission, error) {
 	return ms, nil
 }
 
+func (s *MissionService) GetScheduledMissions() ([]*Mission, error) {
+	var ms []*Mission
+	err := s.Where("start_time > ? OR status IN ?", time.Time{}, []MissionStatus{MissionStatusInProgress, MissionStatusPaused}).
+		Where("status IN ?", []MissionStatus{MissionStatusPending, MissionStatusInProgress, MissionStatusPaused}).
+		Order("start_time asc").Find(&ms).Error
+	if err != nil {
+		return nil, err
+	}
+	return ms, nil
+}
+
+func (s *MissionService) StartMission(id uint, at time.Time) error {
+	return s.Model(&Mission{}).Where("id = ?", id).Updates(map[string]any{
+		"status":     MissionStatusInProgress,
+		"start_time": at,
+	}).Error
+}
+
+// EndMission 使用结构体更新，Result 才会按 json 序列化
+func (s *MissionService) EndMission(id uint, status MissionStatus, result *MissionResult, at time.Time) error {
+	return s.Model(&Mission{}).Where("id = ?", id).Select("status", "end_time", "result").
+		Updates(&Mission{Status: status, EndTime: at, Result: result}).Error
+}
+
+// --- MissionTemplateIface 实现 ---
+func (s *MissionTemplateService) AddMissionTemplate(t *MissionTemplate) error {
+	return s.Create(t).Error
+}
+
+func (s *MissionTemplateService) GetMissionTemplate(id uint) (*MissionTemplate, error) {
+	var t MissionTemplate
+	if err := s.First(&t, id).Error; err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (s *MissionTemplateService) GetMissionTemplateList() ([]*MissionTemplate, error) {
+	var list []*MissionTemplate
+	if err := s.Order("id asc").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// UpdateMissionTemplate 更新 t.ID 对应的模板，零值同样会写入
+func (s *MissionTemplateService) UpdateMissionTemplate(t *MissionTemplate) error {
+	return s.Model(t).Select("name", "desc", "duration", "success_rate", "physics_model", "hazard",
+		"scenario_id", "accidents", "programs", "setting", "status", "criteria").Updates(t).Error
+}
+
+func (s *MissionTemplateService) DeleteMissionTemplate(id uint) error {
+	return s.Delete(&MissionTemplate{}, id).Error
+}
+
 // --- SystemStateIface 实现 ---
+func (s *SystemStateService) AddSystemState(missionID uint, setting RocketSetting, status RocketStatus) (*SystemState, error) {
+	ss := &SystemState{
+		MissionID:     missionID,
+		RocketSetting: setting,
+		RocketStatus:  status,
+	}
+	if err := s.Create(ss).Error; err != nil {
+		return nil, err
+	}
+	return ss, nil
+}
+
 func (s *SystemStateService) GetSystemState(missionID uint) (*SystemState, error) {
 	var ss SystemState
 	if err := s.Where("mission_id = ?", missionID).First(&ss).Error; err != nil {
@@ -91,24 +162,81 @@ func (s *SystemStateService) GetSystemState(missionID uint) (*SystemState, error
 }
 
 func (s *SystemStateService) UpdateSystemSetting(missionID uint, setting RocketSetting) error {
-	return s.Model(&SystemState{}).Where("mission_id = ?", missionID).Updates(setting).Error
+	columns, err := columnsOf(s.DB, &setting)
+	if err != nil {
+		return err
+	}
+	return s.Model(&SystemState{}).Where("mission_id = ?", missionID).Updates(columns).Error
 }
 
 func (s *SystemStateService) UpdateSystemStatus(missionID uint, status RocketStatus) error {
-	return s.Model(&SystemState{}).Where("mission_id = ?", missionID).Updates(status).Error
+	columns, err := columnsOf(s.DB, &status)
+	if err != nil {
+		return err
+	}
+	return s.Model(&SystemState{}).Where("mission_id = ?", missionID).Updates(columns).Error
+}
+
+// columnsOf 将结构体转换为 列名 -> 值 的映射。
+// Updates(struct) 会忽略 false、0 等零值，导致开关关闭、燃料耗尽等状态无法写入数据库。
+func columnsOf(db *gorm.DB, value any) (map[string]any, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(value); err != nil {
+		return nil, err
+	}
+	rv := reflect.Indirect(reflect.ValueOf(value))
+	columns := make(map[string]any, len(stmt.Schema.Fields))
+	for _, field := range stmt.Schema.Fields {
+		if field.DBName == "" {
+			continue
+		}
+		columns[field.DBName], _ = field.ValueOf(context.Background(), rv)
+	}
+	return columns, nil
 }
 
 // --- CustomProgramIface 实现 ---
-func (s *CustomProgramService) GetCusomProgram(id uint) (ProgramSteps, error) {
+func (s *CustomProgramService) AddCustomProgram(name, desc string, steps ProgramSteps) (*CustomProgram, error) {
+	cp := &CustomProgram{Name: name, Desc: desc}
+	if err := cp.Steps.Set(steps); err != nil {
+		return nil, err
+	}
+	if err := s.Create(cp).Error; err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+func (s *CustomProgramService) GetCustomProgram(id uint) (*CustomProgram, error) {
 	var cp CustomProgram
 	if err := s.First(&cp, id).Error; err != nil {
 		return nil, err
 	}
-	var steps ProgramSteps
-	if err := cp.Steps.AssignTo(&steps); err != nil {
+	return &cp, nil
+}
+
+func (s *CustomProgramService) GetCustomProgramList() ([]*CustomProgram, error) {
+	var cps []*CustomProgram
+	if err := s.Order("id asc").Find(&cps).Error; err != nil {
 		return nil, err
 	}
-	return steps, nil
+	return cps, nil
+}
+
+func (s *CustomProgramService) UpdateCustomProgram(id uint, name, desc string, steps ProgramSteps) error {
+	var stepsJSON pgtype.JSONB
+	if err := stepsJSON.Set(steps); err != nil {
+		return err
+	}
+	return s.Model(&CustomProgram{}).Where("id = ?", id).Updates(map[string]any{
+		"name":  name,
+		"desc":  desc,
+		"steps": stepsJSON,
+	}).Error
+}
+
+func (s *CustomProgramService) DeleteCustomProgram(id uint) error {
+	return s.Delete(&CustomProgram{}, id).Error
 }
 
 // --- EventIface 实现 ---
@@ -126,6 +254,21 @@ func (s *EventService) AddEvent(missionID uint, eventType EventType, value strin
 	return e, nil
 }
 
+func (s *EventService) AddInjectedEvent(missionID uint, eventType EventType, value string) (*Event, error) {
+	e := &Event{
+		MissionID: missionID,
+		Type:      eventType,
+		Value:     value,
+		CreatedBy: "system",
+		Status:    EventStatusPending,
+		Injected:  true,
+	}
+	if err := s.Create(e).Error; err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
 func (s *EventService) AddSubEvent(missionID, parentID uint, eventType EventType, value string, createdBy string) (*Event, error) {
 	e := &Event{
 		MissionID: missionID,
@@ -135,27 +278,133 @@ func (s *EventService) AddSubEvent(missionID, parentID uint, eventType EventType
 		CreatedBy: createdBy,
 		Status:    EventStatusPending,
 	}
+	var injected []bool
+	if err := s.Model(&Event{}).Where("id = ?", parentID).Pluck("injected", &injected).Error; err != nil {
+		return nil, err
+	}
+	e.Injected = len(injected) > 0 && injected[0]
 	if err := s.Create(e).Error; err != nil {
 		return nil, err
 	}
 	return e, nil
 }
 
+// UpdateEventStatus 更新事件状态，并记录开始执行和结束的时间
 func (s *EventService) UpdateEventStatus(id uint, status EventStatus) error {
-	return s.Model(&Event{}).Where("id = ?", id).Update("status", status).Error
+	updates := map[string]any{"status": status}
+	switch status {
+	case EventStatusInProgress:
+		updates["started_at"] = gorm.Expr("COALESCE(started_at, ?)", time.Now())
+	case EventStatusCompleted, EventStatusFailed, EventStatusCancelled:
+		// 没有经过 InProgress 的事件以创建时间作为开始时间
+		updates["started_at"] = gorm.Expr("COALESCE(started_at, created_at)")
+		updates["finished_at"] = time.Now()
+	}
+	return s.Model(&Event{}).Where("id = ?", id).Updates(updates).Error
+}
+
+func (s *EventService) UpdateEventDesc(id uint, desc string) error {
+	return s.Model(&Event{}).Where("id = ?", id).Update("desc", desc).Error
+}
+
+// GetRecentEvents 返回任务最近的 limit 个事件（包括子事件），按发生顺序排列
+func (s *EventService) GetRecentEvents(missionID uint, limit int) ([]*Event, error) {
+	var es []*Event
+	if err := s.Where("mission_id = ? AND NOT injected", missionID).Order("id desc").Limit(limit).Find(&es).Error; err != nil {
+		return nil, err
+	}
+	slices.Reverse(es)
+	return es, nil
+}
+
+// GetEventList 按 ID 升序返回满足条件的事件，使用 ID 作为分页游标
+func (s *EventService) GetEventList(missionID uint, filter EventFilter) ([]*Event, error) {
+	query := s.Where("mission_id = ?", missionID)
+	if len(filter.Types) > 0 {
+		query = query.Where("type IN ?", filter.Types)
+	}
+	if filter.Status != nil {
+		query = query.Where("status = ?", *filter.Status)
+	}
+	if filter.CreatedBy != "" {
+		query = query.Where("created_by = ?", filter.CreatedBy)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("created_at >= ?", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		query = query.Where("created_at < ?", filter.Until)
+	}
+	if filter.PartOf != nil {
+		query = query.Where("part_of = ?", *filter.PartOf)
+	}
+	if filter.Cursor > 0 {
+		query = query.Where("id > ?", filter.Cursor)
+	}
+	if !filter.IncludeInjected {
+		query = query.Where("NOT injected")
+	}
+
+	var es []*Event
+	if err := query.Order("id asc").Limit(filter.Limit).Find(&es).Error; err != nil {
+		return nil, err
+	}
+	return es, nil
+}
+
+func (s *EventService) GetEvent(id uint) (*Event, error) {
+	var e Event
+	if err := s.First(&e, id).Error; err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// GetSubEvents 返回 parentIDs 中所有事件的直接子事件，按 ID 升序排列
+func (s *EventService) GetSubEvents(parentIDs []uint) ([]*Event, error) {
+	var es []*Event
+	if err := s.Where("part_of IN ?", parentIDs).Order("id asc").Find(&es).Error; err != nil {
+		return nil, err
+	}
+	return es, nil
 }
 
 // --- AccidentIface 实现 ---
-func (s *AccidentService) GetRandomAccident() (ProgramSteps, error) {
+func (s *AccidentService) AddAccident(a *Accident) error {
+	return s.Create(a).Error
+}
+
+func (s *AccidentService) GetAccident(id uint) (*Accident, error) {
 	var a Accident
-	if err := s.Order("RANDOM()").First(&a).Error; err != nil {
+	if err := s.First(&a, id).Error; err != nil {
 		return nil, err
 	}
-	var steps ProgramSteps
-	if err := a.Steps.AssignTo(&steps); err != nil {
+	return &a, nil
+}
+
+func (s *AccidentService) GetAccidentByName(name string) (*Accident, error) {
+	var a Accident
+	if err := s.Where("name = ?", name).First(&a).Error; err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (s *AccidentService) GetAccidentList() ([]*Accident, error) {
+	var list []*Accident
+	if err := s.Order("id asc").Find(&list).Error; err != nil {
 		return nil, err
 	}
-	return steps, nil
+	return list, nil
+}
+
+// UpdateAccident 更新 a.ID 对应的事故，零值（例如 Severity 为 Minor、Phases 为空）同样会写入
+func (s *AccidentService) UpdateAccident(a *Accident) error {
+	return s.Model(a).Select("name", "desc", "weight", "severity", "phases", "steps").Updates(a).Error
+}
+
+func (s *AccidentService) DeleteAccident(id uint) error {
+	return s.Delete(&Accident{}, id).Error
 }
 
 func (s *DiagnosticService) CreateDiagnostic(missionID uint, createdBy, desc string, result any) (*Diagnostic, error) {
@@ -196,6 +445,62 @@ func (s *DiagnosticService) UpdateDiagnosticStatus(id uint, status DiagnosticSta
 	return s.Model(&Diagnostic{}).Where("id = ?", id).Update("status", status).Error
 }
 
+// --- AlarmIface 实现 ---
+func (s *AlarmService) AddAlarm(missionID uint, code string, level AlarmLevel, desc, raisedBy string, simTime float64) (*Alarm, error) {
+	a := &Alarm{
+		MissionID:     missionID,
+		Code:          code,
+		Level:         level,
+		Status:        AlarmStatusActive,
+		Desc:          desc,
+		RaisedBy:      raisedBy,
+		RaisedSimTime: simTime,
+	}
+	if err := s.Create(a).Error; err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (s *AlarmService) GetAlarm(id uint) (*Alarm, error) {
+	var a Alarm
+	if err := s.First(&a, id).Error; err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (s *AlarmService) GetAlarmList(missionID uint, activeOnly bool) ([]*Alarm, error) {
+	var as []*Alarm
+	query := s.Where("mission_id = ?", missionID)
+	if activeOnly {
+		query = query.Where("status <> ?", AlarmStatusCleared)
+	}
+	if err := query.Order("created_at desc").Find(&as).Error; err != nil {
+		return nil, err
+	}
+	return as, nil
+}
+
+func (s *AlarmService) AcknowledgeAlarm(id uint, user string, simTime float64) error {
+	return s.Model(&Alarm{}).Where("id = ?", id).Updates(map[string]any{
+		"status":             AlarmStatusAcknowledged,
+		"acknowledged_by":    user,
+		"acknowledged_at":    time.Now(),
+		"responded_sim_time": gorm.Expr("COALESCE(responded_sim_time, ?)", simTime),
+	}).Error
+}
+
+// ClearAlarm 清除告警，没有确认直接清除时清除的时间即为响应时间
+func (s *AlarmService) ClearAlarm(id uint, user string, simTime float64) error {
+	return s.Model(&Alarm{}).Where("id = ?", id).Updates(map[string]any{
+		"status":             AlarmStatusCleared,
+		"cleared_by":         user,
+		"cleared_at":         time.Now(),
+		"responded_sim_time": gorm.Expr("COALESCE(responded_sim_time, ?)", simTime),
+	}).Error
+}
+
 // --- 工厂函数，返回所有接口实现 ---
 type GormDBService struct {
 	*gorm.DB
@@ -205,16 +510,126 @@ type GormDBService struct {
 	*EventService
 	*AccidentService
 	*DiagnosticService
+	*AlarmService
+	*PresetService
+	*ScenarioService
+	*InjectionAuditService
+	*MissionTemplateService
+	*TelemetryService
 }
 
 func NewGormDBService(db *gorm.DB) Iface {
 	return &GormDBService{
-		DB:                   db,
-		MissionService:       &MissionService{db},
-		SystemStateService:   &SystemStateService{db},
-		CustomProgramService: &CustomProgramService{db},
-		EventService:         &EventService{db},
-		AccidentService:      &AccidentService{db},
-		DiagnosticService:    &DiagnosticService{db},
+		DB:                     db,
+		MissionService:         &MissionService{db},
+		SystemStateService:     &SystemStateService{db},
+		CustomProgramService:   &CustomProgramService{db},
+		EventService:           &EventService{db},
+		AccidentService:        &AccidentService{db},
+		DiagnosticService:      &DiagnosticService{db},
+		AlarmService:           &AlarmService{db},
+		PresetService:          &PresetService{db},
+		ScenarioService:        &ScenarioService{db},
+		InjectionAuditService:  &InjectionAuditService{db},
+		MissionTemplateService: &MissionTemplateService{db},
+		TelemetryService:       &TelemetryService{db},
+	}
+}
+
+// --- PresetIface 实现 ---
+func (s *PresetService) AddPreset(name, desc string, setting RocketSetting) (*SystemPreset, error) {
+	preset := &SystemPreset{Name: name, Desc: desc, RocketSetting: setting}
+	if err := s.Create(preset).Error; err != nil {
+		return nil, err
+	}
+	return preset, nil
+}
+
+func (s *PresetService) GetPreset(id uint) (*SystemPreset, error) {
+	var preset SystemPreset
+	if err := s.First(&preset, id).Error; err != nil {
+		return nil, err
+	}
+	return &preset, nil
+}
+
+func (s *PresetService) GetPresetList() ([]*SystemPreset, error) {
+	var presets []*SystemPreset
+	if err := s.Order("id asc").Find(&presets).Error; err != nil {
+		return nil, err
+	}
+	return presets, nil
+}
+
+func (s *PresetService) UpdatePreset(id uint, name, desc string, setting RocketSetting) error {
+	columns, err := columnsOf(s.DB, &setting)
+	if err != nil {
+		return err
+	}
+	columns["name"], columns["desc"] = name, desc
+	return s.Model(&SystemPreset{}).Where("id = ?", id).Updates(columns).Error
+}
+
+func (s *PresetService) DeletePreset(id uint) error {
+	return s.Delete(&SystemPreset{}, id).Error
+}
+
+// --- ScenarioIface 实现 ---
+func (s *ScenarioService) AddScenario(sc *Scenario) error {
+	return s.Create(sc).Error
+}
+
+func (s *ScenarioService) GetScenario(id uint) (*Scenario, error) {
+	var sc Scenario
+	if err := s.First(&sc, id).Error; err != nil {
+		return nil, err
+	}
+	return &sc, nil
+}
+
+func (s *ScenarioService) GetScenarioList() ([]*Scenario, error) {
+	var list []*Scenario
+	if err := s.Order("id asc").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// UpdateScenario 更新 sc.ID 对应的剧本，零值同样会写入
+func (s *ScenarioService) UpdateScenario(sc *Scenario) error {
+	return s.Model(sc).Select("name", "desc", "disable_random_accidents", "events").Updates(sc).Error
+}
+
+func (s *ScenarioService) DeleteScenario(id uint) error {
+	return s.Delete(&Scenario{}, id).Error
+}
+
+// --- InjectionAuditIface 实现 ---
+func (s *InjectionAuditService) AddInjectionAudit(a *InjectionAudit) error {
+	return s.Create(a).Error
+}
+
+func (s *InjectionAuditService) GetInjectionAuditList(missionID uint) ([]*InjectionAudit, error) {
+	var list []*InjectionAudit
+	query := s.DB
+	if missionID != 0 {
+		query = query.Where("mission_id = ?", missionID)
+	}
+	if err := query.Order("created_at desc").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// --- TelemetryIface 实现 ---
+func (s *TelemetryService) AddTelemetrySample(missionID uint, status RocketStatus) error {
+	return s.Create(NewTelemetrySample(missionID, status)).Error
+}
+
+func (s *TelemetryService) GetTelemetrySamples(missionID uint) ([]*TelemetrySample, error) {
+	var list []*TelemetrySample
+	if err := s.Where("mission_id = ?", missionID).Order("elapsed_time asc, id asc").Find(&list).Error; err != nil {
+		return nil, err
 	}
+	return list, nil
 }
@@ -61,13 +61,21 @@ func setupEcho(db db.Iface, logger *zap.Logger) (e *echo.Echo) {
 	healthEndpoint := apiGroup.GET("/health", func(c echo.Context) error { return c.JSON(http.StatusOK, map[string]string{"status": "ok"}) })
 	healthEndpoint.Name = "Health check route"
 
-	missionHandler := controller.NewMissionHandler(db)
+	mission.InitMissionService(db)
+
+	missionHandler := controller.NewMissionHandler(db, mission.MissionServiceInstance)
 	missionAPI := apiGroup.Group("/mission")
 	missionAPI.Use(InjectUser())
 	missionAPI.GET("/:id", missionHandler.GetMission)
 	missionAPI.GET("", missionHandler.GetMissionList)
 	missionAPI.POST("", missionHandler.AddMission)
 	missionAPI.PATCH("/:id", missionHandler.UpdateMissionStatus)
+	missionAPI.POST("/:id/clone", missionHandler.CloneMission)
+	missionAPI.GET("/:id/debrief", missionHandler.GetDebrief)
+
+	eventHandler := controller.NewEventHandler(db)
+	missionAPI.GET("/:id/events", eventHandler.GetEventList)
+	missionAPI.GET("/:id/events/:event_id/tree", eventHandler.GetEventTree)
 
 	diagnosticHandler := controller.NewDiagnosticHandler(db)
 	diagnosticAPI := apiGroup.Group("/diagnostic")
@@ -76,12 +84,70 @@ func setupEcho(db db.Iface, logger *zap.Logger) (e *echo.Echo) {
 	diagnosticAPI.GET("", diagnosticHandler.GetDiagnosticList)
 	diagnosticAPI.POST("", diagnosticHandler.CreateDiagnostic)
 
-	mission.InitMissionService(db)
+	alarmHandler := controller.NewAlarmHandler(db)
+	alarmAPI := apiGroup.Group("/alarm")
+	alarmAPI.Use(InjectUser())
+	alarmAPI.GET("/:id", alarmHandler.GetAlarm)
+	alarmAPI.GET("", alarmHandler.GetAlarmList)
+
+	programHandler := controller.NewProgramHandler(db)
+	programAPI := apiGroup.Group("/program")
+	programAPI.Use(InjectUser())
+	programAPI.GET("/:id", programHandler.GetProgram)
+	programAPI.GET("", programHandler.GetProgramList)
+	programAPI.POST("", programHandler.AddProgram)
+	programAPI.POST("/simulate", programHandler.SimulateProgram)
+	programAPI.PUT("/:id", programHandler.UpdateProgram)
+	programAPI.DELETE("/:id", programHandler.DeleteProgram)
+
+	presetHandler := controller.NewPresetHandler(db)
+	presetAPI := apiGroup.Group("/preset")
+	presetAPI.Use(InjectUser())
+	presetAPI.GET("/:id", presetHandler.GetPreset)
+	presetAPI.GET("", presetHandler.GetPresetList)
+	presetAPI.POST("", presetHandler.AddPreset)
+	presetAPI.PUT("/:id", presetHandler.UpdatePreset)
+	presetAPI.DELETE("/:id", presetHandler.DeletePreset)
+
+	accidentHandler := controller.NewAccidentHandler(db)
+	accidentAPI := apiGroup.Group("/accident")
+	accidentAPI.Use(InjectUser())
+	accidentAPI.GET("/:id", accidentHandler.GetAccident)
+	accidentAPI.GET("", accidentHandler.GetAccidentList)
+	accidentAPI.POST("", accidentHandler.AddAccident)
+	accidentAPI.PUT("/:id", accidentHandler.UpdateAccident)
+	accidentAPI.DELETE("/:id", accidentHandler.DeleteAccident)
+
+	scenarioHandler := controller.NewScenarioHandler(db)
+	scenarioAPI := apiGroup.Group("/scenario")
+	scenarioAPI.Use(InjectUser())
+	scenarioAPI.GET("/:id", scenarioHandler.GetScenario)
+	scenarioAPI.GET("", scenarioHandler.GetScenarioList)
+	scenarioAPI.POST("", scenarioHandler.AddScenario)
+	scenarioAPI.PUT("/:id", scenarioHandler.UpdateScenario)
+	scenarioAPI.DELETE("/:id", scenarioHandler.DeleteScenario)
+
+	templateHandler := controller.NewTemplateHandler(db)
+	templateAPI := apiGroup.Group("/template")
+	templateAPI.Use(InjectUser())
+	templateAPI.GET("/:id", templateHandler.GetTemplate)
+	templateAPI.GET("", templateHandler.GetTemplateList)
+	templateAPI.POST("", templateHandler.AddTemplate)
+	templateAPI.PUT("/:id", templateHandler.UpdateTemplate)
+	templateAPI.DELETE("/:id", templateHandler.DeleteTemplate)
+
 	rocketHandler := controller.NewRocketController(mission.MissionServiceInstance)
 	rocketAPI := apiGroup.Group("/rocket")
 	rocketAPI.Use(InjectUser())
 	rocketAPI.GET("", rocketHandler.JoinMission)
 
+	instructorHandler := controller.NewInstructorHandler(db, mission.MissionServiceInstance)
+	instructorAPI := apiGroup.Group("/instructor")
+	instructorAPI.Use(InjectUser(), RequireInstructor())
+	instructorAPI.POST("/mission/:id/inject", instructorHandler.Inject)
+	instructorAPI.GET("/mission/:id/ws", instructorHandler.InjectWs)
+	instructorAPI.GET("/audit", instructorHandler.GetAuditList)
+
 	// iterate all routes and log them
 	for _, r := range e.Routes() {
 		logger.Info("route", zap.String("name", r.Name), zap.String("path", r.Path))
@@ -14,6 +14,7 @@ import (
 	"github.com/eli-yip/rocket-control/db"
 	"github.com/eli-yip/rocket-control/log"
 	"github.com/eli-yip/rocket-control/migrate"
+	"github.com/eli-yip/rocket-control/mission"
 	"github.com/eli-yip/rocket-control/version"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
@@ -63,6 +64,9 @@ func main() {
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer stop()
 
+	// 按任务的开始时间和持续时间自动开始、结束任务
+	go mission.NewScheduler(dbService, mission.MissionServiceInstance).Run(ctx)
+
 	// Start echo server
 	go func() {
 		logger.Info("Start server now!", zap.String("address", ":8080"), zap.String("version", version.Version))
@@ -2,6 +2,7 @@ package main
 
 import (
 	"net/http"
+	"slices"
 	"time"
 
 	"github.com/labstack/echo/v4"
@@ -95,6 +96,23 @@ func InjectUser() echo.MiddlewareFunc {
 			logger.Info("user info", zap.String("username", username), zap.String("nickname", nickname))
 			c.Set("username", username)
 			c.Set("nickname", nickname)
+			c.Set("instructor", slices.Contains(config.C.Mission.Instructors, username))
+			return next(c)
+		}
+	}
+}
+
+// RequireInstructor 只允许配置中的教员访问，需要在 InjectUser 之后使用
+func RequireInstructor() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			logger := controller.ExtractLogger(c)
+
+			username, _ := c.Get("username").(string)
+			if !controller.IsInstructor(c) {
+				logger.Error("user is not an instructor", zap.String("username", username))
+				return c.JSON(http.StatusForbidden, controller.WrapResp("instructor only"))
+			}
 			return next(c)
 		}
 	}
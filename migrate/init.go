@@ -1,5 +1,29 @@
 package migrate
 
-import "gorm.io/gorm"
+import (
+	"gorm.io/gorm"
 
-func MigrateDB(db *gorm.DB) (err error) { return }
+	"github.com/eli-yip/rocket-control/db"
+)
+
+func MigrateDB(gormDB *gorm.DB) (err error) {
+	if err = gormDB.AutoMigrate(
+		&db.Mission{},
+		&db.SystemState{},
+		&db.CustomProgram{},
+		&db.Event{},
+		&db.Accident{},
+		&db.Diagnostic{},
+		&db.Alarm{},
+		&db.SystemPreset{},
+		&db.Scenario{},
+		&db.InjectionAudit{},
+		&db.MissionTemplate{},
+		&db.TelemetrySample{},
+	); err != nil {
+		return err
+	}
+
+	// 事件历史按 (mission_id, id) 做游标分页
+	return gormDB.Exec("CREATE INDEX IF NOT EXISTS idx_events_mission_cursor ON events (mission_id, id)").Error
+}
@@ -0,0 +1,207 @@
+package mission
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/eli-yip/rocket-control/db"
+	"github.com/eli-yip/rocket-control/models"
+)
+
+const accidentTimeWindow = 5 * time.Minute
+
+const (
+	defaultMissionDuration = 60 * time.Minute // 任务没有设置 Duration 时，成功率按这个时长折算
+	hazardNominalTemp      = 20.0             // 温度不增加事故率的上限
+)
+
+// accidentTask 是一次已经发生、等待执行的事故
+type accidentTask struct {
+	event models.Event    // 父事件，类型为 EventTypeAccident
+	steps db.ProgramSteps // 事故对火箭造成的影响，作为子事件执行
+}
+
+func (s *SingleMissionService) accident() {
+	ticker := s.clock.NewTicker(accidentTimeWindow)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C():
+			if s.randomAccidentsDisabled() {
+				continue
+			}
+			s.lock.Lock()
+			hazard := accidentHazard(s.info, *s.settings, *s.status)
+			s.lock.Unlock()
+			if !shouldAccident(accidentTimeWindow, hazard) {
+				continue
+			}
+			a, steps, err := s.pickAccident()
+			if err != nil {
+				s.logger.Error("failed to pick accident", zap.Error(err))
+				continue
+			}
+			if a == nil {
+				s.logger.Info("no accident applicable in current phase")
+				continue
+			}
+			s.logger.Info("accident occurred", zap.String("accident", a.Name))
+			s.scheduleAccident(a, steps, false)
+		case <-s.done:
+			s.logger.Info("accident check stopped")
+			return
+		}
+	}
+}
+
+// pickAccident 按权重从任务允许、当前飞行阶段可能发生的事故中抽取一个，没有可用的事故时返回 nil
+func (s *SingleMissionService) pickAccident() (*db.Accident, db.ProgramSteps, error) {
+	list, err := s.db.GetAccidentList()
+	if err != nil {
+		return nil, nil, err
+	}
+	phase := s.currentPhase()
+	candidates := make([]*db.Accident, 0, len(list))
+	for _, a := range list {
+		if a.Weight > 0 && a.InPhase(phase) && s.info.AllowsAccident(a.Name) {
+			candidates = append(candidates, a)
+		}
+	}
+	a := pickWeighted(candidates, rand.Float64())
+	if a == nil {
+		return nil, nil, nil
+	}
+	steps, err := a.ProgramSteps()
+	if err != nil {
+		return nil, nil, err
+	}
+	return a, steps, nil
+}
+
+// pickWeighted 按权重选择事故，r 为 [0, 1) 的随机数
+func pickWeighted(list []*db.Accident, r float64) *db.Accident {
+	total := 0.0
+	for _, a := range list {
+		total += a.Weight
+	}
+	if total <= 0 {
+		return nil
+	}
+	target := r * total
+	for _, a := range list {
+		if target -= a.Weight; target < 0 {
+			return a
+		}
+	}
+	return list[len(list)-1]
+}
+
+// scheduleAccident 记录事故父事件，并交给 processAccident 执行，返回父事件 ID，失败时返回 0。
+// injected 为真时事故由教员注入，Client 只能看到它的效果。
+func (s *SingleMissionService) scheduleAccident(a *db.Accident, steps db.ProgramSteps, injected bool) uint {
+	var e *db.Event
+	var err error
+	if injected {
+		e, err = s.db.AddInjectedEvent(s.info.ID, db.EventTypeAccident, a.Name)
+	} else {
+		e, err = s.db.AddEvent(s.info.ID, db.EventTypeAccident, a.Name, "system")
+	}
+	if err != nil {
+		s.logger.Error("failed to add accident event", zap.Error(err))
+		return 0
+	}
+	_ = s.db.UpdateEventDesc(e.ID, a.Desc)
+
+	task := accidentTask{
+		event: models.Event{
+			ID:        e.ID,
+			EventType: db.EventTypeAccident,
+			Status:    db.EventStatusPending,
+			Value:     a.Name,
+			CreatedBy: "system",
+			Injected:  injected,
+		},
+		steps: steps,
+	}
+	select {
+	case s.accidentEvent <- task:
+		return e.ID
+	case <-s.done:
+		_ = s.db.UpdateEventStatus(e.ID, db.EventStatusCancelled)
+		return 0
+	}
+}
+
+// processAccident 依次执行发生的事故，和 processComplexEvent 类似，事故的每一步作为子事件执行
+func (s *SingleMissionService) processAccident() {
+	for {
+		select {
+		case <-s.done:
+			s.logger.Info("accident processor stopped")
+			return
+		case task := <-s.accidentEvent:
+			s.runAccident(task)
+		}
+	}
+}
+
+func (s *SingleMissionService) runAccident(task accidentTask) {
+	event := task.event
+	logger := s.logger.With(zap.Uint("e_id", event.ID))
+	logger.Info("processing accident", zap.String("accident", event.Value), zap.Int("steps", len(task.steps)))
+
+	event.Status = db.EventStatusInProgress
+	_ = s.db.UpdateEventStatus(event.ID, db.EventStatusInProgress)
+	s.broadcast(event)
+
+	// 事故不能被操作员取消，只会随任务停止而终止
+	s.finishComplexEvent(event, s.runSteps(context.Background(), event, task.steps, logger))
+}
+
+// baseHazard 由任务成功率计算基础事故率（每分钟）。
+// 把随机事故看作泊松过程，整个任务期间不发生事故的概率 exp(-λ·Duration) 等于成功率，即 λ = -ln(SuccessRate/100) / Duration。
+func baseHazard(m *db.Mission) float64 {
+	rate := m.SuccessRate
+	if rate <= 0 {
+		rate = db.DefaultSuccessRate
+	}
+	if rate >= 100 {
+		return 0
+	}
+	duration := time.Duration(m.Duration) * time.Minute
+	if duration <= 0 {
+		duration = defaultMissionDuration
+	}
+	return -math.Log(rate/100) / duration.Minutes()
+}
+
+// accidentHazard 返回当前的事故率（每分钟），基础事故率受稳定器和推力、温度带来的压力调节
+func accidentHazard(m *db.Mission, setting db.RocketSetting, status db.RocketStatus) float64 {
+	cfg := db.DefaultHazardConfig
+	if m.Hazard != nil {
+		cfg = *m.Hazard
+	}
+
+	// 稳定器为 50 时不影响事故率
+	stabilizer := math.Max(0, 1+cfg.Stabilizer*(0.5-clamp(setting.Stabilizer, 0, 100)/100))
+	// 推力超过 50、温度超过常温时压力增加
+	thrustStress := math.Max(0, clamp(setting.Thrust, 0, 100)-50) / 50
+	tempStress := clamp((status.TemperatureLevel-hazardNominalTemp)/(tempMax-hazardNominalTemp), 0, 2)
+	stress := 1 + cfg.Thrust*thrustStress + cfg.Temperature*tempStress
+
+	return baseHazard(m) * stabilizer * stress
+}
+
+// shouldAccident 判断在 duration 内是否发生事故，hazard 为每分钟的事故率
+func shouldAccident(duration time.Duration, hazard float64) bool {
+	if hazard <= 0 {
+		return false
+	}
+	probability := 1.0 - math.Exp(-hazard*duration.Minutes())
+	return rand.Float64() < probability
+}
@@ -0,0 +1,166 @@
+package mission
+
+import (
+	"fmt"
+	"strconv"
+
+	"go.uber.org/zap"
+
+	"github.com/eli-yip/rocket-control/db"
+	"github.com/eli-yip/rocket-control/models"
+)
+
+// 阈值定义
+const (
+	hullMin     = 20.0
+	fuelMin     = 10.0
+	oxygenMin   = 10.0
+	tempMax     = 90.0
+	pressureMin = 15.0
+	pressureMax = 95.0
+)
+
+// 手动触发的告警代码
+const manualAlarmCode = "manual"
+
+// thresholdCrossing 表示一次状态突破阈值
+type thresholdCrossing struct {
+	code  string
+	level db.AlarmLevel
+	desc  string
+}
+
+// thresholdCrossings 返回从 old 变化到 cur 时新突破的阈值
+func thresholdCrossings(old, cur db.RocketStatus) (crossings []thresholdCrossing) {
+	if old.HullLevel >= hullMin && cur.HullLevel < hullMin {
+		crossings = append(crossings, thresholdCrossing{"hull_low", db.AlarmLevelCritical, "Hull integrity low."})
+	}
+	if old.FuelLevel >= fuelMin && cur.FuelLevel < fuelMin {
+		crossings = append(crossings, thresholdCrossing{"fuel_low", db.AlarmLevelWarning, "Fuel low."})
+	}
+	if old.OxygenLevel >= oxygenMin && cur.OxygenLevel < oxygenMin {
+		crossings = append(crossings, thresholdCrossing{"oxygen_low", db.AlarmLevelCritical, "Oxygen low."})
+	}
+	if old.TemperatureLevel <= tempMax && cur.TemperatureLevel > tempMax {
+		crossings = append(crossings, thresholdCrossing{"temperature_high", db.AlarmLevelCritical, "Temperature high."})
+	}
+	if (old.PressureLevel >= pressureMin && cur.PressureLevel < pressureMin) ||
+		(old.PressureLevel <= pressureMax && cur.PressureLevel > pressureMax) {
+		crossings = append(crossings, thresholdCrossing{"pressure_abnormal", db.AlarmLevelWarning, "Pressure abnormal."})
+	}
+	return crossings
+}
+
+// raiseAlarm 触发告警并广播，同一代码的告警未清除前不会重复触发。
+// 检查和写入在 alarmLock 中完成，并发触发同一代码的告警时只会写入一次。
+func (s *SingleMissionService) raiseAlarm(code string, level db.AlarmLevel, desc, user string) (*db.Alarm, error) {
+	s.alarmLock.Lock()
+	defer s.alarmLock.Unlock()
+
+	if code != manualAlarmCode {
+		active, err := s.db.GetAlarmList(s.info.ID, true)
+		if err != nil {
+			s.logger.Error("failed to get active alarms", zap.Error(err))
+			return nil, err
+		}
+		for _, a := range active {
+			if a.Code == code {
+				return a, nil
+			}
+		}
+	}
+
+	alarm, err := s.db.AddAlarm(s.info.ID, code, level, desc, user, s.alarmSimTime())
+	if err != nil {
+		s.logger.Error("failed to add alarm", zap.Error(err))
+		return nil, err
+	}
+	s.logger.Info("alarm raised", zap.Uint("alarm", alarm.ID), zap.String("code", code))
+
+	if user == "system" {
+		// 自动告警没有对应的操作员事件，单独记录以便回放
+		s.recordEvent(models.Event{
+			EventType: db.EventTypeAlarmSet,
+			Value:     strconv.FormatUint(uint64(alarm.ID), 10),
+			CreatedBy: user,
+		}, db.EventStatusCompleted)
+	}
+	s.broadcastAlarm(db.EventTypeAlarmSet, alarm.ID, user)
+	return alarm, nil
+}
+
+// alarmSimTime 返回当前的模拟时间（秒），加上距离上一次状态推进经过的模拟时间，
+// 使告警的响应时间不受状态推进间隔的影响。模拟时间不论任务状态都会增加，
+// 没有开始（Pending）的任务中触发的告警也按实际的响应时间评估
+func (s *SingleMissionService) alarmSimTime() float64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	t := s.status.SimElapsed
+	if !s.lastStep.IsZero() {
+		t += min(s.clock.Now().Sub(s.lastStep), statusTickInterval).Seconds()
+	}
+	return t
+}
+
+// broadcastAlarm 广播告警状态变化，前端根据 Value 中的 AlarmID 从 RESTful Endpoint 获取告警详情
+func (s *SingleMissionService) broadcastAlarm(eventType db.EventType, alarmID uint, user string) {
+	s.broadcast(models.Event{
+		ID:        alarmID,
+		EventType: eventType,
+		Status:    db.EventStatusCompleted,
+		Value:     strconv.FormatUint(uint64(alarmID), 10),
+		CreatedBy: user,
+	})
+}
+
+// handleAlarmEvent 处理操作员发来的告警事件：
+// set_alarm 的 Value 为告警描述，ack_alarm 与 clear_alarm 的 Value 为 AlarmID
+func (s *SingleMissionService) handleAlarmEvent(event models.Event, logger *zap.Logger) bool {
+	var err error
+	switch event.EventType {
+	case db.EventTypeAlarmSet:
+		_, err = s.raiseAlarm(manualAlarmCode, db.AlarmLevelWarning, event.Value, event.CreatedBy)
+	case db.EventTypeAlarmAck, db.EventTypeAlarmClear:
+		err = s.updateAlarm(event)
+	}
+	if err != nil {
+		logger.Warn("failed to handle alarm event", zap.String("value", event.Value), zap.Error(err))
+		s.failEvent(event, err.Error())
+		return false
+	}
+	_ = s.db.UpdateEventStatus(event.ID, db.EventStatusCompleted)
+	return true
+}
+
+func (s *SingleMissionService) updateAlarm(event models.Event) error {
+	id, err := strconv.ParseUint(event.Value, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid alarm id: %w", err)
+	}
+	alarm, err := s.db.GetAlarm(uint(id))
+	if err != nil {
+		return fmt.Errorf("failed to get alarm: %w", err)
+	}
+	if alarm.MissionID != s.info.ID {
+		return fmt.Errorf("alarm %d does not belong to mission %d", alarm.ID, s.info.ID)
+	}
+
+	switch event.EventType {
+	case db.EventTypeAlarmAck:
+		if alarm.Status != db.AlarmStatusActive {
+			return fmt.Errorf("alarm %d is not active", alarm.ID)
+		}
+		err = s.db.AcknowledgeAlarm(alarm.ID, event.CreatedBy, s.alarmSimTime())
+	case db.EventTypeAlarmClear:
+		if alarm.Status == db.AlarmStatusCleared {
+			return fmt.Errorf("alarm %d already cleared", alarm.ID)
+		}
+		err = s.db.ClearAlarm(alarm.ID, event.CreatedBy, s.alarmSimTime())
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update alarm: %w", err)
+	}
+
+	s.broadcastAlarm(event.EventType, alarm.ID, event.CreatedBy)
+	return nil
+}
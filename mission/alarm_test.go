@@ -0,0 +1,75 @@
+package mission
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eli-yip/rocket-control/db"
+	"go.uber.org/zap"
+)
+
+func TestAlarmResponseUsesSimTime(t *testing.T) {
+	// 加入后没有开始的任务（Pending）也按实际的响应时间评估
+	for _, status := range []db.MissionStatus{db.MissionStatusPending, db.MissionStatusInProgress} {
+		testAlarmResponse(t, status)
+	}
+}
+
+func testAlarmResponse(t *testing.T, status db.MissionStatus) {
+	s, fdb, clock := newTestMissionWithStatus(t, status)
+	go s.adjustStatus()
+	tick := func(n float64) {
+		t.Helper()
+		waitFor(t, "status ticker", func() bool { return clock.pendingTimers() == 1 })
+		clock.Advance(statusTickInterval)
+		waitFor(t, "physics tick", func() bool { return fdb.systemStatus(1).SimElapsed == n })
+	}
+
+	tick(1)
+	alarm, err := s.raiseAlarm("hull_low", db.AlarmLevelCritical, "Hull integrity low.", "system")
+	if err != nil {
+		t.Fatalf("failed to raise alarm: %v", err)
+	}
+
+	// 暂停期间不计入响应时间
+	s.handlePauseEvent(addTestEvent(t, fdb, db.EventTypePause, "", "commander"), zap.NewNop())
+	clock.Advance(time.Minute)
+	s.handlePauseEvent(addTestEvent(t, fdb, db.EventTypeResume, "", "commander"), zap.NewNop())
+	tick(2)
+	tick(3)
+
+	ack := addTestEvent(t, fdb, db.EventTypeAlarmAck, strconv.FormatUint(uint64(alarm.ID), 10), "trainee")
+	if !s.handleAlarmEvent(ack, zap.NewNop()) {
+		t.Fatal("alarm acknowledge failed")
+	}
+
+	alarms, _ := fdb.GetAlarmList(1, false)
+	operators, score, count := evaluateResponses(alarms, 30)
+	if count != 1 || len(operators) != 1 || operators[0].Operator != "trainee" {
+		t.Fatalf("mission status %d: operators %+v, alarms %d", status, operators, count)
+	}
+	if operators[0].AvgResponse != 2 || score != 100 {
+		t.Errorf("mission status %d: response %v s, score %v, want 2 s and 100", status, operators[0].AvgResponse, score)
+	}
+}
+
+func TestConcurrentAlarmsAreDeduplicated(t *testing.T) {
+	s, fdb, _ := newTestMission(t)
+	fdb.alarmListDelay = 10 * time.Millisecond
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = s.raiseAlarm("hull_low", db.AlarmLevelCritical, "Hull integrity low.", "system")
+		}()
+	}
+	wg.Wait()
+
+	if alarms, _ := fdb.GetAlarmList(1, true); len(alarms) != 1 {
+		t.Fatalf("%d active hull_low alarms, want 1", len(alarms))
+	}
+}
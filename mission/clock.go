@@ -0,0 +1,329 @@
+package mission
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock 是任务使用的模拟时钟，任务中的所有计时都通过 Clock 完成，
+// 这样可以加速（或放慢）任务，也可以在测试中手动推进时间。
+type Clock interface {
+	// Now 返回当前的模拟时间
+	Now() time.Time
+	// After 在经过 d 模拟时间后发送当前模拟时间
+	After(d time.Duration) <-chan time.Time
+	// NewTicker 返回一个每经过 d 模拟时间触发一次的 Ticker
+	NewTicker(d time.Duration) Ticker
+	// Speed 返回模拟时间相对真实时间的倍率
+	Speed() float64
+	// SetSpeed 设置模拟时间相对真实时间的倍率
+	SetSpeed(speed float64)
+	// Pause 冻结模拟时间，所有计时器停止计时
+	Pause()
+	// Resume 恢复模拟时间，计时器从暂停处继续
+	Resume()
+	Paused() bool
+	// Stop 释放所有等待中的计时器，任务停止时调用
+	Stop()
+}
+
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+const (
+	MinSimSpeed = 0.5
+	MaxSimSpeed = 20.0
+)
+
+// ScaledClock 按倍率换算真实时间，倍率变化时正在等待的计时器会重新计算剩余时间
+type ScaledClock struct {
+	mu       sync.Mutex
+	base     time.Time // 上次倍率变化时的模拟时间
+	realBase time.Time // 上次倍率变化时的真实时间
+	speed    float64
+	paused   bool
+	changed  chan struct{} // 倍率变化或暂停、恢复时关闭并替换，唤醒等待中的计时器
+	stopped  chan struct{}
+	stopOnce sync.Once
+}
+
+func NewScaledClock() *ScaledClock {
+	now := time.Now()
+	return &ScaledClock{
+		base:     now,
+		realBase: now,
+		speed:    1,
+		changed:  make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+}
+
+func (c *ScaledClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.nowLocked()
+}
+
+func (c *ScaledClock) nowLocked() time.Time {
+	if c.paused {
+		return c.base
+	}
+	elapsed := time.Since(c.realBase)
+	return c.base.Add(time.Duration(float64(elapsed) * c.speed))
+}
+
+func (c *ScaledClock) Speed() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.speed
+}
+
+func (c *ScaledClock) SetSpeed(speed float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rebaseLocked()
+	c.speed = speed
+	c.notifyLocked()
+}
+
+func (c *ScaledClock) Pause() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rebaseLocked()
+	c.paused = true
+	c.notifyLocked()
+}
+
+func (c *ScaledClock) Resume() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rebaseLocked()
+	c.paused = false
+	c.notifyLocked()
+}
+
+func (c *ScaledClock) Paused() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.paused
+}
+
+// rebaseLocked 以当前时刻为新的换算起点
+func (c *ScaledClock) rebaseLocked() {
+	c.base, c.realBase = c.nowLocked(), time.Now()
+}
+
+// notifyLocked 唤醒等待中的计时器重新计算剩余时间
+func (c *ScaledClock) notifyLocked() {
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+func (c *ScaledClock) Stop() { c.stopOnce.Do(func() { close(c.stopped) }) }
+
+// waitUntil 阻塞到模拟时间到达 deadline，时钟或 cancel 停止时返回 false
+func (c *ScaledClock) waitUntil(deadline time.Time, cancel <-chan struct{}) bool {
+	for {
+		c.mu.Lock()
+		remaining := deadline.Sub(c.nowLocked())
+		speed, paused, changed := c.speed, c.paused, c.changed
+		c.mu.Unlock()
+		if remaining <= 0 {
+			return true
+		}
+
+		if paused {
+			// 暂停期间只等待恢复
+			select {
+			case <-changed:
+				continue
+			case <-cancel:
+				return false
+			case <-c.stopped:
+				return false
+			}
+		}
+
+		timer := time.NewTimer(time.Duration(float64(remaining) / speed))
+		select {
+		case <-timer.C:
+		case <-changed:
+			timer.Stop()
+		case <-cancel:
+			timer.Stop()
+			return false
+		case <-c.stopped:
+			timer.Stop()
+			return false
+		}
+	}
+}
+
+func (c *ScaledClock) After(d time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	deadline := c.Now().Add(d)
+	go func() {
+		if c.waitUntil(deadline, nil) {
+			ch <- c.Now()
+		}
+	}()
+	return ch
+}
+
+func (c *ScaledClock) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for ScaledClock.NewTicker")
+	}
+	t := &scaledTicker{c: make(chan time.Time, 1), stop: make(chan struct{})}
+	go func() {
+		next := c.Now().Add(d)
+		for c.waitUntil(next, t.stop) {
+			select {
+			case t.c <- next:
+			default: // 和 time.Ticker 一样，接收方跟不上时丢弃
+			}
+			next = next.Add(d)
+		}
+	}()
+	return t
+}
+
+type scaledTicker struct {
+	c        chan time.Time
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+func (t *scaledTicker) C() <-chan time.Time { return t.c }
+func (t *scaledTicker) Stop()               { t.stopOnce.Do(func() { close(t.stop) }) }
+
+// ManualClock 只在调用 Advance 时前进，用于确定性地驱动任务。
+// 倍率只会被记录，不影响时间推进；暂停期间 Advance 不推进时间。
+type ManualClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	speed   float64
+	paused  bool
+	waiters []*manualWaiter
+}
+
+type manualWaiter struct {
+	deadline time.Time
+	period   time.Duration // 大于 0 时为 Ticker
+	c        chan time.Time
+}
+
+func NewManualClock(start time.Time) *ManualClock {
+	return &ManualClock{now: start, speed: 1}
+}
+
+func (c *ManualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *ManualClock) Speed() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.speed
+}
+
+func (c *ManualClock) SetSpeed(speed float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.speed = speed
+}
+
+func (c *ManualClock) Pause() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.paused = true
+}
+
+func (c *ManualClock) Resume() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.paused = false
+}
+
+func (c *ManualClock) Paused() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.paused
+}
+
+func (c *ManualClock) Stop() {}
+
+func (c *ManualClock) After(d time.Duration) <-chan time.Time {
+	return c.addWaiter(d, 0).c
+}
+
+func (c *ManualClock) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for ManualClock.NewTicker")
+	}
+	w := c.addWaiter(d, d)
+	return &manualTicker{clock: c, w: w}
+}
+
+func (c *ManualClock) addWaiter(d, period time.Duration) *manualWaiter {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	w := &manualWaiter{deadline: c.now.Add(d), period: period, c: make(chan time.Time, 1)}
+	if d <= 0 && period == 0 {
+		w.c <- c.now
+		return w
+	}
+	c.waiters = append(c.waiters, w)
+	return w
+}
+
+// Advance 将时间推进 d，并按时间顺序触发到期的计时器
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.paused {
+		return
+	}
+	target := c.now.Add(d)
+	for {
+		sort.Slice(c.waiters, func(i, j int) bool { return c.waiters[i].deadline.Before(c.waiters[j].deadline) })
+		if len(c.waiters) == 0 || c.waiters[0].deadline.After(target) {
+			break
+		}
+		w := c.waiters[0]
+		c.now = w.deadline
+		select {
+		case w.c <- c.now:
+		default:
+		}
+		if w.period > 0 {
+			w.deadline = w.deadline.Add(w.period)
+		} else {
+			c.waiters = c.waiters[1:]
+		}
+	}
+	c.now = target
+}
+
+type manualTicker struct {
+	clock *ManualClock
+	w     *manualWaiter
+}
+
+func (t *manualTicker) C() <-chan time.Time { return t.w.c }
+
+func (t *manualTicker) Stop() {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	for i, w := range t.clock.waiters {
+		if w == t.w {
+			t.clock.waiters = append(t.clock.waiters[:i], t.clock.waiters[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package mission
+
+import (
+	"testing"
+	"time"
+
+	"github.com/eli-yip/rocket-control/db"
+	"github.com/eli-yip/rocket-control/models"
+	"go.uber.org/zap"
+)
+
+func TestManualClockPauseResume(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewManualClock(start)
+	after := clock.After(2 * time.Second)
+
+	clock.Advance(time.Second)
+	clock.Pause()
+	clock.Advance(10 * time.Second)
+	if got := clock.Now(); !got.Equal(start.Add(time.Second)) {
+		t.Fatalf("paused clock advanced to %v", got)
+	}
+	select {
+	case <-after:
+		t.Fatal("timer fired while paused")
+	default:
+	}
+
+	clock.Resume()
+	clock.Advance(time.Second)
+	select {
+	case got := <-after:
+		if !got.Equal(start.Add(2 * time.Second)) {
+			t.Fatalf("timer fired at %v", got)
+		}
+	default:
+		t.Fatal("timer did not fire after resume")
+	}
+}
+
+func TestManualClockTicker(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewManualClock(start)
+	ticker := clock.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for i := 1; i <= 3; i++ {
+		clock.Advance(time.Second)
+		if got := <-ticker.C(); !got.Equal(start.Add(time.Duration(i) * time.Second)) {
+			t.Fatalf("tick %d at %v", i, got)
+		}
+	}
+}
+
+// addTestEvent 在数据库中创建事件，返回可以交给处理函数的 models.Event
+func addTestEvent(t *testing.T, fdb *fakeDB, eventType db.EventType, value, user string) models.Event {
+	t.Helper()
+	e, err := fdb.AddEvent(1, eventType, value, user)
+	if err != nil {
+		t.Fatalf("failed to add event: %v", err)
+	}
+	return models.Event{ID: e.ID, EventType: eventType, Value: value, CreatedBy: user}
+}
+
+func TestPauseResumeEvent(t *testing.T) {
+	s, fdb, clock := newTestMission(t)
+	start := clock.Now()
+
+	pause := addTestEvent(t, fdb, db.EventTypePause, "", "commander")
+	s.handlePauseEvent(pause, zap.NewNop())
+	if !clock.Paused() || s.info.Status != db.MissionStatusPaused {
+		t.Fatalf("mission not paused: clock paused %v, status %v", clock.Paused(), s.info.Status)
+	}
+	if got := fdb.event(pause.ID).Status; got != db.EventStatusCompleted {
+		t.Fatalf("pause event status %v", got)
+	}
+
+	clock.Advance(time.Minute)
+	if !clock.Now().Equal(start) {
+		t.Fatal("sim time advanced while paused")
+	}
+
+	again := addTestEvent(t, fdb, db.EventTypePause, "", "commander")
+	s.handlePauseEvent(again, zap.NewNop())
+	if got := fdb.event(again.ID).Status; got != db.EventStatusFailed {
+		t.Fatalf("second pause event status %v", got)
+	}
+
+	resume := addTestEvent(t, fdb, db.EventTypeResume, "", "commander")
+	s.handlePauseEvent(resume, zap.NewNop())
+	if clock.Paused() || s.info.Status != db.MissionStatusInProgress {
+		t.Fatalf("mission not resumed: clock paused %v, status %v", clock.Paused(), s.info.Status)
+	}
+	clock.Advance(time.Second)
+	if !clock.Now().Equal(start.Add(time.Second)) {
+		t.Fatal("sim time did not advance after resume")
+	}
+}
+
+func TestSimSpeedEvent(t *testing.T) {
+	s, fdb, clock := newTestMission(t)
+
+	tests := []struct {
+		user   string
+		value  string
+		status db.EventStatus
+		speed  float64
+	}{
+		{"commander", "5", db.EventStatusCompleted, 5},
+		{"trainee", "2", db.EventStatusFailed, 5},
+		{"commander", "30", db.EventStatusFailed, 5},
+		{"commander", "fast", db.EventStatusFailed, 5},
+		{"commander", "0.5", db.EventStatusCompleted, 0.5},
+	}
+	for _, tt := range tests {
+		event := addTestEvent(t, fdb, db.EventTypeSimSpeed, tt.value, tt.user)
+		s.handleSimSpeedEvent(event, zap.NewNop())
+		if got := fdb.event(event.ID).Status; got != tt.status {
+			t.Errorf("%s sets speed %s: event status %v, want %v", tt.user, tt.value, got, tt.status)
+		}
+		if got := clock.Speed(); got != tt.speed {
+			t.Errorf("%s sets speed %s: clock speed %v, want %v", tt.user, tt.value, got, tt.speed)
+		}
+	}
+}
+
+func TestLaunchCountdown(t *testing.T) {
+	s, fdb, clock := newTestMission(t)
+
+	launch := addTestEvent(t, fdb, db.EventTypeLanuch, "", "commander")
+	if !s.handleFlightCommand(launch, zap.NewNop()) {
+		t.Fatal("launch rejected before lift-off")
+	}
+
+	for i := countdownSeconds; i >= 1; i-- {
+		waitFor(t, "countdown timer", func() bool { return clock.pendingTimers() == 1 })
+		if phase := s.currentPhase(); phase != db.FlightPhaseCountdown {
+			t.Fatalf("phase %s with %d seconds remaining", phase, i)
+		}
+		if i == countdownSeconds/2 {
+			// 暂停期间倒计时不前进
+			clock.Pause()
+			clock.Advance(time.Minute)
+			clock.Resume()
+			if phase := s.currentPhase(); phase != db.FlightPhaseCountdown {
+				t.Fatalf("countdown continued while paused, phase %s", phase)
+			}
+		}
+		clock.Advance(time.Second)
+	}
+
+	waitFor(t, "lift-off", func() bool { return fdb.event(launch.ID).Status == db.EventStatusCompleted })
+	if phase := s.currentPhase(); phase != db.FlightPhaseAscent {
+		t.Fatalf("phase %s after countdown", phase)
+	}
+	if !s.status.Launched {
+		t.Fatal("rocket not marked as launched")
+	}
+
+	again := addTestEvent(t, fdb, db.EventTypeLanuch, "", "commander")
+	if s.handleFlightCommand(again, zap.NewNop()) {
+		t.Fatal("second launch accepted")
+	}
+}
+
+func TestPhysicsTick(t *testing.T) {
+	s, fdb, clock := newTestMission(t)
+	before := fdb.systemStatus(1)
+	want := s.physics.Step(*s.settings, before, statusTickInterval)
+
+	go s.adjustStatus()
+	waitFor(t, "status ticker", func() bool { return clock.pendingTimers() == 1 })
+
+	// 暂停期间不推进物理模型
+	clock.Pause()
+	clock.Advance(5 * statusTickInterval)
+	clock.Resume()
+
+	clock.Advance(statusTickInterval)
+	waitFor(t, "physics tick", func() bool { return fdb.systemStatus(1) != before })
+
+	got := fdb.systemStatus(1)
+	if got.FuelLevel != want.FuelLevel || got.HullLevel != want.HullLevel || got.OxygenLevel != want.OxygenLevel {
+		t.Fatalf("status after one tick %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,326 @@
+package mission
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/eli-yip/rocket-control/db"
+	"gorm.io/gorm"
+)
+
+const (
+	// accidentResponseWindow 是事故结束后仍然计入处理过程的时间
+	accidentResponseWindow = 2 * time.Minute
+	// maxCurvePoints 是每条资源曲线最多保留的点数
+	maxCurvePoints = 200
+)
+
+// Debrief 是任务的复盘报告，由任务记录、事件、告警、诊断和遥测采样汇总而成
+type Debrief struct {
+	Mission     *db.Mission      `json:"mission"` // 包含评估结果 Result
+	GeneratedAt time.Time        `json:"generated_at"`
+	Setting     db.RocketSetting `json:"setting"` // 最终设置
+	Status      db.RocketStatus  `json:"status"`  // 最终状态
+	Timeline    []DebriefEvent   `json:"timeline"`
+	Programs    []ProgramOutcome `json:"programs"`
+	Accidents   []AccidentReport `json:"accidents"`
+	Alarms      []*db.Alarm      `json:"alarms"`
+	Diagnostics []*db.Diagnostic `json:"diagnostics"`
+	Resources   []ResourceCurve  `json:"resources"`
+}
+
+// DebriefEvent 是时间线上的一个事件，Offset 为相对任务开始的秒数
+type DebriefEvent struct {
+	ID        uint           `json:"id"`
+	At        time.Time      `json:"at"`
+	Offset    float64        `json:"offset"`
+	Type      db.EventType   `json:"type"`
+	Value     string         `json:"value"`
+	Desc      string         `json:"desc"`
+	CreatedBy string         `json:"created_by"`
+	Status    db.EventStatus `json:"status"`
+	Injected  bool           `json:"injected,omitempty"` // 教员注入的事件
+}
+
+// ProgramOutcome 是一次自定义程序运行的结果
+type ProgramOutcome struct {
+	DebriefEvent
+	ProgramID   uint       `json:"program_id"`
+	Name        string     `json:"name"`
+	FinishedAt  *time.Time `json:"finished_at"`
+	Steps       int        `json:"steps"`        // 已经执行的顶层步骤数量
+	FailedSteps int        `json:"failed_steps"` // 失败的顶层步骤数量
+	Reason      string     `json:"reason"`       // 失败原因
+}
+
+// AccidentReport 是一次事故及其处理过程：事故的影响、期间触发的告警和操作员的响应
+type AccidentReport struct {
+	DebriefEvent
+	FinishedAt   *time.Time     `json:"finished_at"`
+	Effects      []DebriefEvent `json:"effects"`
+	Alarms       []*db.Alarm    `json:"alarms"`
+	Responses    []DebriefEvent `json:"responses"`
+	ResponseTime *float64       `json:"response_time"` // 事故发生到操作员第一次响应的秒数，没有响应时为空
+}
+
+// ResourceCurve 是一项状态在飞行中的变化，T 为任务经过时间（秒）
+type ResourceCurve struct {
+	Field  string       `json:"field"`
+	Start  float64      `json:"start"`
+	End    float64      `json:"end"`
+	Min    float64      `json:"min"`
+	Max    float64      `json:"max"`
+	Points []CurvePoint `json:"points"`
+}
+
+type CurvePoint struct {
+	T float64 `json:"t"`
+	V float64 `json:"v"`
+}
+
+// curveFields 是资源曲线包含的状态，名称与 RocketStatus 的字段名一致
+var curveFields = []struct {
+	name  string
+	value func(*db.TelemetrySample) float64
+}{
+	{"HullLevel", func(t *db.TelemetrySample) float64 { return t.HullLevel }},
+	{"FuelLevel", func(t *db.TelemetrySample) float64 { return t.FuelLevel }},
+	{"OxygenLevel", func(t *db.TelemetrySample) float64 { return t.OxygenLevel }},
+	{"TemperatureLevel", func(t *db.TelemetrySample) float64 { return t.TemperatureLevel }},
+	{"PressureLevel", func(t *db.TelemetrySample) float64 { return t.PressureLevel }},
+	{"AltitudeLevel", func(t *db.TelemetrySample) float64 { return t.AltitudeLevel }},
+	{"VelocityLevel", func(t *db.TelemetrySample) float64 { return t.VelocityLevel }},
+}
+
+// BuildDebrief 生成任务的复盘报告，任务可以还在进行中。includeInjected 为假时不包含教员注入的事件
+func BuildDebrief(dbService db.Iface, missionID uint, includeInjected bool) (*Debrief, error) {
+	m, err := dbService.GetMission(missionID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrMissionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mission: %w", err)
+	}
+
+	d := &Debrief{Mission: m, GeneratedAt: time.Now()}
+	initialSetting, initialStatus := m.InitialState()
+	d.Setting, d.Status = initialSetting, initialStatus
+	state, err := dbService.GetSystemState(missionID)
+	if err == nil {
+		d.Setting, d.Status = state.RocketSetting, state.RocketStatus
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to get system state: %w", err)
+	}
+
+	// Limit 为 -1 表示不限制数量
+	var topLevel uint
+	events, err := dbService.GetEventList(missionID, db.EventFilter{PartOf: &topLevel, Limit: -1, IncludeInjected: includeInjected})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get events: %w", err)
+	}
+	alarms, err := dbService.GetAlarmList(missionID, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get alarms: %w", err)
+	}
+	// GetAlarmList 按触发时间倒序返回，报告中按发生顺序排列
+	slices.Reverse(alarms)
+	d.Alarms = alarms
+	if d.Diagnostics, err = dbService.GetDiagnosticList(missionID); err != nil {
+		return nil, fmt.Errorf("failed to get diagnostics: %w", err)
+	}
+	samples, err := dbService.GetTelemetrySamples(missionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get telemetry samples: %w", err)
+	}
+
+	start := m.StartTime
+	if start.IsZero() {
+		start = m.CreatedAt
+	}
+	d.Timeline = make([]DebriefEvent, 0, len(events))
+	for _, e := range events {
+		d.Timeline = append(d.Timeline, newDebriefEvent(e, start))
+	}
+	if d.Programs, err = debriefPrograms(dbService, events, start); err != nil {
+		return nil, err
+	}
+	if d.Accidents, err = debriefAccidents(dbService, events, alarms, start); err != nil {
+		return nil, err
+	}
+	d.Resources = resourceCurves(samples, initialStatus, d.Status)
+	return d, nil
+}
+
+func newDebriefEvent(e *db.Event, start time.Time) DebriefEvent {
+	return DebriefEvent{
+		ID:        e.ID,
+		At:        e.CreatedAt,
+		Offset:    e.CreatedAt.Sub(start).Seconds(),
+		Type:      e.Type,
+		Value:     e.Value,
+		Desc:      e.Desc,
+		CreatedBy: e.CreatedBy,
+		Status:    e.Status,
+		Injected:  e.Injected,
+	}
+}
+
+// subEventsOf 返回 parents 的直接子事件，按父事件 ID 分组
+func subEventsOf(dbService db.Iface, parents []*db.Event) (map[uint][]*db.Event, error) {
+	if len(parents) == 0 {
+		return nil, nil
+	}
+	ids := make([]uint, 0, len(parents))
+	for _, e := range parents {
+		ids = append(ids, e.ID)
+	}
+	children, err := dbService.GetSubEvents(ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sub events: %w", err)
+	}
+	byParent := make(map[uint][]*db.Event, len(parents))
+	for _, c := range children {
+		byParent[c.PartOf] = append(byParent[c.PartOf], c)
+	}
+	return byParent, nil
+}
+
+func eventsOfType(events []*db.Event, eventType db.EventType) []*db.Event {
+	var list []*db.Event
+	for _, e := range events {
+		if e.Type == eventType {
+			list = append(list, e)
+		}
+	}
+	return list
+}
+
+// debriefPrograms 汇总自定义程序的运行结果，父事件的 Desc 在开始运行后为程序名称，加载失败时为失败原因
+func debriefPrograms(dbService db.Iface, events []*db.Event, start time.Time) ([]ProgramOutcome, error) {
+	runs := eventsOfType(events, db.EventTypeCustomAdd)
+	steps, err := subEventsOf(dbService, runs)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[uint]string)
+	outcomes := make([]ProgramOutcome, 0, len(runs))
+	for _, e := range runs {
+		o := ProgramOutcome{DebriefEvent: newDebriefEvent(e, start), FinishedAt: e.FinishedAt}
+		if id, err := strconv.ParseUint(e.Value, 10, 64); err == nil {
+			o.ProgramID = uint(id)
+			name, ok := names[o.ProgramID]
+			if !ok {
+				if p, err := dbService.GetCustomProgram(o.ProgramID); err == nil {
+					name = p.Name
+				}
+				names[o.ProgramID] = name
+			}
+			o.Name = name
+		}
+		for _, step := range steps[e.ID] {
+			o.Steps++
+			if step.Status == db.EventStatusFailed {
+				o.FailedSteps++
+				if o.Reason == "" {
+					o.Reason = step.Desc
+				}
+			}
+		}
+		if e.Status == db.EventStatusFailed && o.Reason == "" && e.Desc != o.Name {
+			o.Reason = e.Desc
+		}
+		outcomes = append(outcomes, o)
+	}
+	return outcomes, nil
+}
+
+// debriefAccidents 汇总事故及其处理过程，事故开始到结束后 accidentResponseWindow 内触发的告警和操作员发起的事件都计入处理过程
+func debriefAccidents(dbService db.Iface, events []*db.Event, alarms []*db.Alarm, start time.Time) ([]AccidentReport, error) {
+	accidents := eventsOfType(events, db.EventTypeAccident)
+	effects, err := subEventsOf(dbService, accidents)
+	if err != nil {
+		return nil, err
+	}
+
+	reports := make([]AccidentReport, 0, len(accidents))
+	for _, e := range accidents {
+		r := AccidentReport{DebriefEvent: newDebriefEvent(e, start), FinishedAt: e.FinishedAt}
+		for _, effect := range effects[e.ID] {
+			r.Effects = append(r.Effects, newDebriefEvent(effect, start))
+		}
+
+		until := time.Now()
+		if e.FinishedAt != nil {
+			until = e.FinishedAt.Add(accidentResponseWindow)
+		}
+		inWindow := func(t time.Time) bool { return !t.Before(e.CreatedAt) && !t.After(until) }
+		for _, a := range alarms {
+			if inWindow(a.CreatedAt) {
+				r.Alarms = append(r.Alarms, a)
+			}
+		}
+		for _, ev := range events {
+			if !isOperatorAction(ev) || !inWindow(ev.CreatedAt) {
+				continue
+			}
+			r.Responses = append(r.Responses, newDebriefEvent(ev, start))
+			if r.ResponseTime == nil {
+				seconds := ev.CreatedAt.Sub(e.CreatedAt).Seconds()
+				r.ResponseTime = &seconds
+			}
+		}
+		reports = append(reports, r)
+	}
+	return reports, nil
+}
+
+// resourceCurves 由遥测采样生成资源曲线，曲线以初始状态开始、以最终状态结束，点数超过 maxCurvePoints 时均匀抽取
+func resourceCurves(samples []*db.TelemetrySample, initial, final db.RocketStatus) []ResourceCurve {
+	points := make([]*db.TelemetrySample, 0, len(samples)+2)
+	points = append(points, db.NewTelemetrySample(0, initial))
+	points = append(points, samples...)
+	if last := points[len(points)-1]; final.ElapsedTime > last.ElapsedTime || len(samples) == 0 {
+		points = append(points, db.NewTelemetrySample(0, final))
+	}
+	if len(points) > maxCurvePoints {
+		stride := float64(len(points)-1) / float64(maxCurvePoints-1)
+		picked := make([]*db.TelemetrySample, 0, maxCurvePoints)
+		for i := range maxCurvePoints {
+			picked = append(picked, points[int(float64(i)*stride+0.5)])
+		}
+		points = picked
+	}
+
+	curves := make([]ResourceCurve, 0, len(curveFields))
+	for _, f := range curveFields {
+		c := ResourceCurve{
+			Field:  f.name,
+			Start:  f.value(points[0]),
+			End:    f.value(points[len(points)-1]),
+			Points: make([]CurvePoint, 0, len(points)),
+		}
+		c.Min, c.Max = c.Start, c.Start
+		for _, p := range points {
+			v := f.value(p)
+			c.Min, c.Max = min(c.Min, v), max(c.Max, v)
+			c.Points = append(c.Points, CurvePoint{T: p.ElapsedTime, V: v})
+		}
+		// 采样之间的极值记录在最终状态中
+		if final.Extremes.Valid {
+			if rng, ok := final.Extremes.Range(f.name); ok {
+				c.Min, c.Max = min(c.Min, rng.Min), max(c.Max, rng.Max)
+			}
+		}
+		curves = append(curves, c)
+	}
+	return curves
+}
+
+// isOperatorAction 返回事件是否是操作员发起的操作，加入和离开任务不计入
+func isOperatorAction(e *db.Event) bool {
+	return e.CreatedBy != "system" && e.Type != db.EventTypeJoin && e.Type != db.EventTypeLeave
+}
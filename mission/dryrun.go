@@ -0,0 +1,332 @@
+package mission
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/eli-yip/rocket-control/db"
+	"github.com/eli-yip/rocket-control/log"
+	"github.com/eli-yip/rocket-control/models"
+	"go.uber.org/zap"
+)
+
+const (
+	maxDryRunDuration = 1 * time.Hour // dry-run 最多模拟的时间，超过后终止，避免没有超时的 wait_until 一直等待
+	maxDryRunEvents   = 10000         // dry-run 最多产生的事件数量
+	dryRunUser        = "dry-run"
+)
+
+// DryRunInput 描述一次 dry-run：从 Setting、Status 出发，用 PhysicsModel 执行 Steps
+type DryRunInput struct {
+	Steps        db.ProgramSteps
+	Setting      db.RocketSetting
+	Status       db.RocketStatus
+	PhysicsModel string
+	CreatedBy    string
+}
+
+type (
+	DryRunResult struct {
+		Status       db.EventStatus   `json:"status"`    // 程序的最终状态
+		Duration     int64            `json:"duration"`  // 程序执行的模拟时间（毫秒）
+		Truncated    bool             `json:"truncated"` // 超过模拟时间或事件数量上限，被提前终止
+		FinalSetting db.RocketSetting `json:"final_setting"`
+		FinalStatus  db.RocketStatus  `json:"final_status"`
+		Timeline     []DryRunSample   `json:"timeline"`     // 每个状态周期的 RocketStatus
+		FailedSteps  []DryRunFailure  `json:"failed_steps"` // 会失败的步骤
+		Crossings    []DryRunCrossing `json:"crossings"`    // 会触发诊断和告警的阈值
+		Events       []*db.Event      `json:"events"`       // 执行过程中产生的全部事件，ID 只在本次 dry-run 中有效
+	}
+
+	DryRunSample struct {
+		Time   int64           `json:"time"` // 距离开始的模拟时间（毫秒），下同
+		Status db.RocketStatus `json:"status"`
+	}
+
+	DryRunFailure struct {
+		Time      int64        `json:"time"`
+		EventID   uint         `json:"event_id"`
+		PartOf    uint         `json:"part_of"`
+		EventType db.EventType `json:"event_type"`
+		Value     string       `json:"value"`
+		Reason    string       `json:"reason"`
+	}
+
+	DryRunCrossing struct {
+		Time  int64         `json:"time"`
+		Code  string        `json:"code"`
+		Level db.AlarmLevel `json:"level"`
+		Desc  string        `json:"desc"`
+	}
+)
+
+// DryRun 在一个不连接客户端、不写数据库的 SingleMissionService 中执行程序。
+// 步骤使用和真实任务相同的处理逻辑，状态由同一个物理模型按 statusTickInterval 推进，
+// 所有等待都由虚拟时钟立即完成，因此结果是确定的。dbService 只用于读取事故、程序、预设和剧本，
+// 事件记录在内存中，其余写操作被丢弃或返回错误。
+func DryRun(dbService db.Iface, in DryRunInput) (*DryRunResult, error) {
+	physics, err := GetPhysicsModel(in.PhysicsModel)
+	if err != nil {
+		return nil, err
+	}
+	if in.CreatedBy == "" {
+		in.CreatedBy = dryRunUser
+	}
+	setting, status := in.Setting, in.Status
+	normalizePhase(&status)
+
+	start := time.Now()
+	clock := &dryRunClock{now: start, stepped: start}
+	rec := &dryRunDB{source: dbService, clock: clock}
+	s := &SingleMissionService{
+		db:       rec,
+		info:     &db.Mission{Name: dryRunUser, CreatedBy: in.CreatedBy, PhysicsModel: physics.Name()},
+		settings: &setting,
+		status:   &status,
+		physics:  physics,
+		clock:    clock,
+		logger:   log.DefaultLogger.With(zap.Bool("dry_run", true)),
+		done:     make(chan struct{}),
+		dryRun:   true,
+	}
+
+	result := &DryRunResult{Timeline: []DryRunSample{{Time: 0, Status: status}}}
+	var once sync.Once
+	stop := func() {
+		once.Do(func() {
+			result.Truncated = true
+			close(s.done)
+		})
+	}
+	rec.stop = stop
+	clock.onTick = func(dt time.Duration) bool {
+		s.lock.Lock()
+		crossings := s.stepStatusLocked(dt)
+		cur := *s.status
+		s.lock.Unlock()
+
+		elapsed := clock.Now().Sub(start)
+		result.Timeline = append(result.Timeline, DryRunSample{Time: elapsed.Milliseconds(), Status: cur})
+		for _, c := range crossings {
+			result.Crossings = append(result.Crossings, DryRunCrossing{
+				Time:  elapsed.Milliseconds(),
+				Code:  c.code,
+				Level: c.level,
+				Desc:  c.desc,
+			})
+		}
+		if elapsed >= maxDryRunDuration {
+			stop()
+			return false
+		}
+		return true
+	}
+
+	root, _ := rec.AddEvent(0, db.EventTypeCustomAdd, dryRunUser, in.CreatedBy)
+	event := models.Event{
+		ID:        root.ID,
+		EventType: db.EventTypeCustomAdd,
+		Value:     dryRunUser,
+		CreatedBy: in.CreatedBy,
+	}
+	ctx, stopProgram := s.startProgram(event, dryRunUser)
+	defer stopProgram()
+	_ = rec.UpdateEventStatus(event.ID, db.EventStatusInProgress)
+
+	result.Status = s.runSteps(ctx, event, in.Steps, s.logger)
+	_ = rec.UpdateEventStatus(event.ID, result.Status)
+
+	result.Duration = clock.Now().Sub(start).Milliseconds()
+	result.FinalSetting, result.FinalStatus = *s.settings, *s.status
+	result.Events = rec.events
+	for _, e := range rec.events {
+		if e.PartOf == 0 || e.Status != db.EventStatusFailed {
+			continue
+		}
+		f := DryRunFailure{
+			EventID:   e.ID,
+			PartOf:    e.PartOf,
+			EventType: e.Type,
+			Value:     e.Value,
+			Reason:    e.Desc,
+		}
+		if e.FinishedAt != nil {
+			f.Time = e.FinishedAt.Sub(start).Milliseconds()
+		}
+		result.FailedSteps = append(result.FailedSteps, f)
+	}
+	sort.SliceStable(result.FailedSteps, func(i, j int) bool { return result.FailedSteps[i].Time < result.FailedSteps[j].Time })
+
+	return result, nil
+}
+
+// dryRunClock 是 dry-run 使用的虚拟时钟：After 立即推进时间，每经过 statusTickInterval 调用一次 onTick。
+// dry-run 中所有调用都在同一个 goroutine 中，不需要加锁。
+type dryRunClock struct {
+	now     time.Time
+	stepped time.Time // 物理模型已经推进到的时间
+	stopped bool
+	onTick  func(dt time.Duration) bool // 返回 false 时时钟停止
+}
+
+func (c *dryRunClock) Now() time.Time { return c.now }
+
+func (c *dryRunClock) After(d time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	if c.stopped {
+		// 停止后不再触发，等待方会从 done 退出
+		return ch
+	}
+	target := c.now.Add(d)
+	for next := c.stepped.Add(statusTickInterval); !next.After(target); next = c.stepped.Add(statusTickInterval) {
+		c.stepped, c.now = next, next
+		if !c.onTick(statusTickInterval) {
+			c.stopped = true
+			return ch
+		}
+	}
+	c.now = target
+	ch <- c.now
+	return ch
+}
+
+// NewTicker 返回的 Ticker 不会触发，dry-run 不运行周期任务
+func (c *dryRunClock) NewTicker(time.Duration) Ticker { return dryRunTicker{} }
+func (c *dryRunClock) Speed() float64                 { return 1 }
+func (c *dryRunClock) SetSpeed(float64)               {}
+func (c *dryRunClock) Pause()                         {}
+func (c *dryRunClock) Resume()                        {}
+func (c *dryRunClock) Paused() bool                   { return false }
+func (c *dryRunClock) Stop()                          { c.stopped = true }
+
+type dryRunTicker struct{}
+
+func (dryRunTicker) C() <-chan time.Time { return nil }
+func (dryRunTicker) Stop()               {}
+
+var errNotInDryRun = errors.New("not available in dry run")
+
+// dryRunDB 在内存中记录事件，丢弃对任务状态的写入，只有事故、程序、预设和剧本从真实数据库读取
+type dryRunDB struct {
+	source db.Iface
+	mu     sync.Mutex
+	clock  Clock
+	events []*db.Event // 第 i 个事件的 ID 为 i+1
+	stop   func()      // 事件数量超过上限时调用
+}
+
+func (r *dryRunDB) GetAccidentByName(name string) (*db.Accident, error) {
+	return r.source.GetAccidentByName(name)
+}
+func (r *dryRunDB) GetAccidentList() ([]*db.Accident, error) { return r.source.GetAccidentList() }
+func (r *dryRunDB) GetCustomProgram(id uint) (*db.CustomProgram, error) {
+	return r.source.GetCustomProgram(id)
+}
+func (r *dryRunDB) GetPreset(id uint) (*db.SystemPreset, error) { return r.source.GetPreset(id) }
+func (r *dryRunDB) GetScenario(id uint) (*db.Scenario, error)   { return r.source.GetScenario(id) }
+
+func (r *dryRunDB) AddEvent(missionID uint, eventType db.EventType, value string, createdBy string) (*db.Event, error) {
+	return r.AddSubEvent(missionID, 0, eventType, value, createdBy)
+}
+
+func (r *dryRunDB) AddInjectedEvent(uint, db.EventType, string) (*db.Event, error) {
+	return nil, errNotInDryRun
+}
+
+func (r *dryRunDB) AddSubEvent(missionID, parentID uint, eventType db.EventType, value string, createdBy string) (*db.Event, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e := &db.Event{
+		MissionID: missionID,
+		CreatedBy: createdBy,
+		PartOf:    parentID,
+		Status:    db.EventStatusPending,
+		Type:      eventType,
+		Value:     value,
+	}
+	e.ID = uint(len(r.events) + 1)
+	e.CreatedAt = r.clock.Now()
+	e.UpdatedAt = e.CreatedAt
+	r.events = append(r.events, e)
+	if len(r.events) > maxDryRunEvents && r.stop != nil {
+		r.stop()
+	}
+	return e, nil
+}
+
+func (r *dryRunDB) event(id uint) *db.Event {
+	if id == 0 || int(id) > len(r.events) {
+		return nil
+	}
+	return r.events[id-1]
+}
+
+func (r *dryRunDB) UpdateEventStatus(id uint, status db.EventStatus) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e := r.event(id)
+	if e == nil {
+		return nil
+	}
+	now := r.clock.Now()
+	e.Status, e.UpdatedAt = status, now
+	switch status {
+	case db.EventStatusInProgress:
+		if e.StartedAt == nil {
+			e.StartedAt = &now
+		}
+	case db.EventStatusCompleted, db.EventStatusFailed, db.EventStatusCancelled:
+		if e.StartedAt == nil {
+			e.StartedAt = &e.CreatedAt
+		}
+		e.FinishedAt = &now
+	}
+	return nil
+}
+
+func (r *dryRunDB) UpdateEventDesc(id uint, desc string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if e := r.event(id); e != nil {
+		e.Desc = desc
+	}
+	return nil
+}
+
+func (r *dryRunDB) GetRecentEvents(_ uint, limit int) ([]*db.Event, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*db.Event(nil), r.events[max(len(r.events)-limit, 0):]...), nil
+}
+
+func (r *dryRunDB) GetEventList(uint, db.EventFilter) ([]*db.Event, error) {
+	return nil, errNotInDryRun
+}
+
+func (r *dryRunDB) UpdateSystemSetting(uint, db.RocketSetting) error { return nil }
+func (r *dryRunDB) UpdateSystemStatus(uint, db.RocketStatus) error   { return nil }
+func (r *dryRunDB) UpdateMissionStatus(uint, db.MissionStatus) error { return nil }
+func (r *dryRunDB) StartMission(uint, time.Time) error               { return errNotInDryRun }
+func (r *dryRunDB) AddTelemetrySample(uint, db.RocketStatus) error   { return nil }
+func (r *dryRunDB) EndMission(uint, db.MissionStatus, *db.MissionResult, time.Time) error {
+	return errNotInDryRun
+}
+
+// dry-run 不触发告警，会触发告警的阈值记录在 DryRunResult.Crossings 中
+func (r *dryRunDB) AddAlarm(uint, string, db.AlarmLevel, string, string, float64) (*db.Alarm, error) {
+	return nil, errNotInDryRun
+}
+func (r *dryRunDB) GetAlarm(uint) (*db.Alarm, error)             { return nil, errNotInDryRun }
+func (r *dryRunDB) GetAlarmList(uint, bool) ([]*db.Alarm, error) { return nil, nil }
+func (r *dryRunDB) AcknowledgeAlarm(uint, string, float64) error { return errNotInDryRun }
+func (r *dryRunDB) ClearAlarm(uint, string, float64) error       { return errNotInDryRun }
+
+func (r *dryRunDB) CreateDiagnostic(missionID uint, createdBy, desc string, result any) (*db.Diagnostic, error) {
+	diag := &db.Diagnostic{MissionID: missionID, CreatedBy: createdBy, Desc: desc}
+	if err := diag.Result.Set(result); err != nil {
+		return nil, err
+	}
+	return diag, nil
+}
@@ -0,0 +1,79 @@
+package mission
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/eli-yip/rocket-control/db"
+)
+
+func TestDryRunDoesNotWriteDB(t *testing.T) {
+	m := &db.Mission{Name: "test", CreatedBy: "commander", Status: db.MissionStatusInProgress}
+	m.ID = 1
+	fdb := newFakeDB(m)
+	setting, status := m.InitialState()
+	if _, err := fdb.AddSystemState(1, setting, status); err != nil {
+		t.Fatal(err)
+	}
+	mission, state := *fdb.missions[1], *fdb.states[1]
+
+	steps := db.ProgramSteps{
+		{EventType: db.EventTypeTest},
+		{EventType: db.EventTypeThrust, Value: "80", Duration: 5000},
+		{EventType: db.EventTypeTriggerComms, Value: "false"},
+		{EventType: db.EventTypeDiagnoseStart, Duration: 1000},
+		{EventType: db.EventTypeFuelChange, Value: "-5"},
+	}
+	result, err := DryRun(fdb, DryRunInput{Steps: steps, Setting: setting, Status: status})
+	if err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+	if result.Status != db.EventStatusCompleted {
+		t.Fatalf("dry run status %v, failed steps %+v", result.Status, result.FailedSteps)
+	}
+	if result.Duration != 6000 {
+		t.Errorf("dry run duration %d ms, want 6000", result.Duration)
+	}
+	if result.FinalSetting.Thrust != 80 || result.FinalSetting.Comms {
+		t.Errorf("final setting %+v", result.FinalSetting)
+	}
+	if len(result.Events) != len(steps)+1 {
+		t.Errorf("dry run recorded %d events, want %d", len(result.Events), len(steps)+1)
+	}
+
+	if len(fdb.events) != 0 {
+		t.Errorf("dry run wrote %d events to the database", len(fdb.events))
+	}
+	if !reflect.DeepEqual(*fdb.missions[1], mission) {
+		t.Errorf("dry run changed the mission: %+v", *fdb.missions[1])
+	}
+	if !reflect.DeepEqual(*fdb.states[1], state) {
+		t.Errorf("dry run changed the system state: %+v", *fdb.states[1])
+	}
+}
+
+func TestDryRunRejectsPhaseCommand(t *testing.T) {
+	setting, status := db.DefaultRocketSetting, db.DefaultRocketStatus
+	steps := db.ProgramSteps{
+		{EventType: db.EventTypeThrust, Value: "50"},
+		{EventType: db.EventTypeAbort},
+		{EventType: db.EventTypeThrust, Value: "0"},
+	}
+	if err := ValidateProgramSteps(steps); err == nil {
+		t.Error("abort accepted as a program step")
+	}
+
+	result, err := DryRun(newFakeDB(), DryRunInput{Steps: steps, Setting: setting, Status: status})
+	if err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+	if result.Status != db.EventStatusFailed {
+		t.Fatalf("dry run status %v, want failed", result.Status)
+	}
+	if len(result.FailedSteps) != 1 || result.FailedSteps[0].EventType != db.EventTypeAbort {
+		t.Errorf("failed steps %+v", result.FailedSteps)
+	}
+	if result.FinalSetting.Thrust != 50 || result.FinalStatus.Phase != db.FlightPhasePreLaunch {
+		t.Errorf("final setting %+v, status %+v", result.FinalSetting, result.FinalStatus)
+	}
+}
@@ -0,0 +1,236 @@
+package mission
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/eli-yip/rocket-control/db"
+)
+
+var ErrInvalidCriteria = errors.New("invalid success criteria")
+
+// ValidateCriteria 检查任务的评估标准
+func ValidateCriteria(c *db.SuccessCriteria) error {
+	if c.ResponseTarget < 0 {
+		return fmt.Errorf("%w: negative response_target", ErrInvalidCriteria)
+	}
+	if c.ResponseWeight < 0 || c.ResponseWeight > 1 {
+		return fmt.Errorf("%w: response_weight must be between 0 and 1", ErrInvalidCriteria)
+	}
+	for i, cr := range c.Criteria {
+		if err := validateCriterion(cr); err != nil {
+			return fmt.Errorf("criterion %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+func validateCriterion(c db.SuccessCriterion) error {
+	if c.Points < 0 {
+		return fmt.Errorf("%w: negative points", ErrInvalidCriteria)
+	}
+	switch c.Kind {
+	case db.CriterionKindReachPhase:
+		if !ValidFlightPhase(c.Phase) {
+			return fmt.Errorf("%w: invalid flight phase %q", ErrInvalidCriteria, c.Phase)
+		}
+	case db.CriterionKindFinal:
+		if _, ok := conditionFields[c.Field]; !ok {
+			return fmt.Errorf("%w: unknown field %q", ErrInvalidCriteria, c.Field)
+		}
+	case db.CriterionKindMin, db.CriterionKindMax:
+		if _, ok := (&db.StatusExtremes{}).Range(c.Field); !ok {
+			return fmt.Errorf("%w: field %q has no recorded range", ErrInvalidCriteria, c.Field)
+		}
+	case db.CriterionKindNoActiveAlarms:
+		return nil
+	default:
+		return fmt.Errorf("%w: unknown kind %q", ErrInvalidCriteria, c.Kind)
+	}
+	if c.Kind != db.CriterionKindReachPhase && !validConditionOp(c.Op) {
+		return fmt.Errorf("%w: unknown operator %q", ErrInvalidCriteria, c.Op)
+	}
+	return nil
+}
+
+// hardFailure 返回无论评估标准如何都视为失败的原因，没有时返回空
+func hardFailure(status db.RocketStatus) string {
+	switch {
+	case status.Phase == db.FlightPhaseAborted:
+		return "mission aborted"
+	case status.HullLevel <= 0:
+		return "hull destroyed"
+	}
+	return ""
+}
+
+// evaluateMission 按任务的评估标准评估最终的设置和状态，不写数据库
+func evaluateMission(dbService missionDB, m *db.Mission, setting db.RocketSetting, status db.RocketStatus, at time.Time) (*db.MissionResult, error) {
+	criteria := m.Criteria
+	if criteria == nil {
+		criteria = &db.SuccessCriteria{}
+	}
+	alarms, err := dbService.GetAlarmList(m.ID, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get alarms: %w", err)
+	}
+	// Limit 为 -1 表示不限制数量
+	phaseEvents, err := dbService.GetEventList(m.ID, db.EventFilter{Types: []db.EventType{db.EventTypePhase}, Limit: -1})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get phase events: %w", err)
+	}
+	reached := []db.FlightPhase{status.Phase}
+	for _, e := range phaseEvents {
+		reached = append(reached, db.FlightPhase(e.Value))
+	}
+	// 范围包含最终状态
+	status.TrackExtremes()
+
+	result := &db.MissionResult{
+		Status:      db.MissionStatusCompleted,
+		Reason:      hardFailure(status),
+		Criteria:    make([]db.CriterionResult, 0, len(criteria.Criteria)),
+		EvaluatedAt: at,
+	}
+
+	var total, earned float64
+	for _, c := range criteria.Criteria {
+		r := evaluateCriterion(c, &setting, &status, reached, alarms)
+		if r.Points == 0 {
+			r.Points = 1
+		}
+		if r.Passed {
+			r.Score = r.Points
+		} else if result.Reason == "" {
+			result.Reason = "criterion not met: " + describeCriterion(c)
+		}
+		total += r.Points
+		earned += r.Score
+		result.Criteria = append(result.Criteria, r)
+	}
+	if result.Reason != "" {
+		result.Status = db.MissionStatusFailed
+	}
+	switch {
+	case total > 0:
+		result.CriteriaScore = earned / total * 100
+	case result.Status == db.MissionStatusCompleted:
+		result.CriteriaScore = 100
+	}
+
+	target := criteria.ResponseTarget
+	if target == 0 {
+		target = db.DefaultResponseTarget
+	}
+	var systemAlarms int
+	result.Operators, result.ResponseScore, systemAlarms = evaluateResponses(alarms, target)
+	result.Score = result.CriteriaScore
+	if criteria.ResponseWeight > 0 && systemAlarms > 0 {
+		result.Score = (1-criteria.ResponseWeight)*result.CriteriaScore + criteria.ResponseWeight*result.ResponseScore
+	}
+	return result, nil
+}
+
+func evaluateCriterion(c db.SuccessCriterion, setting *db.RocketSetting, status *db.RocketStatus, reached []db.FlightPhase, alarms []*db.Alarm) db.CriterionResult {
+	r := db.CriterionResult{SuccessCriterion: c}
+	switch c.Kind {
+	case db.CriterionKindReachPhase:
+		r.Passed = slices.Contains(reached, c.Phase)
+		r.Actual = boolToFloat(r.Passed)
+	case db.CriterionKindFinal:
+		if field, ok := conditionFields[c.Field]; ok {
+			r.Actual = field(setting, status)
+			r.Passed = compare(r.Actual, c.Op, c.Value)
+		}
+	case db.CriterionKindMin, db.CriterionKindMax:
+		if rng, ok := status.Extremes.Range(c.Field); ok {
+			r.Actual = rng.Max
+			if c.Kind == db.CriterionKindMin {
+				r.Actual = rng.Min
+			}
+			r.Passed = compare(r.Actual, c.Op, c.Value)
+		}
+	case db.CriterionKindNoActiveAlarms:
+		for _, a := range alarms {
+			if a.Status != db.AlarmStatusCleared {
+				r.Actual++
+			}
+		}
+		r.Passed = r.Actual == 0
+	}
+	return r
+}
+
+func describeCriterion(c db.SuccessCriterion) string {
+	if c.Desc != "" {
+		return c.Desc
+	}
+	switch c.Kind {
+	case db.CriterionKindReachPhase:
+		return fmt.Sprintf("reach %s", c.Phase)
+	case db.CriterionKindNoActiveAlarms:
+		return "no active alarms"
+	}
+	return fmt.Sprintf("%s %s %s %g", c.Kind, c.Field, c.Op, c.Value)
+}
+
+// responseScore 按确认时间计算单个告警的得分：不超过目标时间为 100，超过后线性下降，到目标时间的 3 倍为 0
+func responseScore(seconds, target float64) float64 {
+	if seconds <= target {
+		return 100
+	}
+	return math.Max(0, 100*(1-(seconds-target)/(2*target)))
+}
+
+// evaluateResponses 统计每个操作员确认系统告警的时间，返回操作员结果、总体响应得分和系统告警数量。
+// 响应时间使用模拟时间（RocketStatus.SimElapsed），不论任务状态，暂停期间不计时，并随模拟速度缩放。
+// 没有被确认或清除的系统告警计 0 分，没有系统告警时总体得分为 100。
+func evaluateResponses(alarms []*db.Alarm, target float64) ([]db.OperatorResult, float64, int) {
+	byOperator := make(map[string]*db.OperatorResult)
+	var count int
+	var sum float64
+	for _, a := range alarms {
+		if a.RaisedBy != "system" {
+			continue
+		}
+		count++
+		// 没有确认直接清除也算作响应
+		if a.RespondedSimTime == nil {
+			continue
+		}
+		by := a.AcknowledgedBy
+		if by == "" {
+			by = a.ClearedBy
+		}
+		seconds := math.Max(0, *a.RespondedSimTime-a.RaisedSimTime)
+		score := responseScore(seconds, target)
+		sum += score
+
+		op, ok := byOperator[by]
+		if !ok {
+			op = &db.OperatorResult{Operator: by}
+			byOperator[by] = op
+		}
+		op.Alarms++
+		op.AvgResponse += seconds
+		op.MaxResponse = math.Max(op.MaxResponse, seconds)
+		op.Score += score
+	}
+
+	operators := make([]db.OperatorResult, 0, len(byOperator))
+	for _, op := range byOperator {
+		op.AvgResponse /= float64(op.Alarms)
+		op.Score /= float64(op.Alarms)
+		operators = append(operators, *op)
+	}
+	sort.Slice(operators, func(i, j int) bool { return operators[i].Operator < operators[j].Operator })
+
+	if count == 0 {
+		return operators, 100, 0
+	}
+	return operators, sum / float64(count), count
+}
@@ -0,0 +1,271 @@
+package mission
+
+import (
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eli-yip/rocket-control/db"
+	"github.com/eli-yip/rocket-control/log"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestMain(m *testing.M) {
+	log.DefaultLogger = zap.NewNop()
+	os.Exit(m.Run())
+}
+
+// fakeDB 在内存中实现任务运行需要的数据库操作，未实现的方法调用时会 panic
+type fakeDB struct {
+	db.Iface
+	mu       sync.Mutex
+	missions map[uint]*db.Mission
+	states   map[uint]*db.SystemState
+	events   []*db.Event // 第 i 个事件的 ID 为 i+1
+	alarms   []*db.Alarm // 第 i 个告警的 ID 为 i+1
+
+	alarmListDelay time.Duration // 模拟查询告警的延迟，用于测试并发触发告警
+	onRecentEvents func()        // 查询最近事件时调用
+	onGetMission   func(id uint) // 查询任务时调用
+	writeErr       error         // 不为空时保存 RocketSetting、RocketStatus 返回该错误
+}
+
+func newFakeDB(missions ...*db.Mission) *fakeDB {
+	f := &fakeDB{missions: make(map[uint]*db.Mission), states: make(map[uint]*db.SystemState)}
+	for _, m := range missions {
+		f.missions[m.ID] = m
+	}
+	return f
+}
+
+func (f *fakeDB) GetMission(id uint) (*db.Mission, error) {
+	if f.onGetMission != nil {
+		f.onGetMission(id)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	m, ok := f.missions[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	info := *m
+	return &info, nil
+}
+
+func (f *fakeDB) UpdateMissionStatus(id uint, status db.MissionStatus) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if m, ok := f.missions[id]; ok {
+		m.Status = status
+	}
+	return nil
+}
+
+func (f *fakeDB) StartMission(id uint, at time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if m, ok := f.missions[id]; ok {
+		m.Status, m.StartTime = db.MissionStatusInProgress, at
+	}
+	return nil
+}
+
+func (f *fakeDB) AddSystemState(missionID uint, setting db.RocketSetting, status db.RocketStatus) (*db.SystemState, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.states[missionID] = &db.SystemState{MissionID: missionID, RocketSetting: setting, RocketStatus: status}
+	state := *f.states[missionID]
+	return &state, nil
+}
+
+func (f *fakeDB) GetSystemState(missionID uint) (*db.SystemState, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s, ok := f.states[missionID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	state := *s
+	return &state, nil
+}
+
+func (f *fakeDB) UpdateSystemSetting(missionID uint, setting db.RocketSetting) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.writeErr != nil {
+		return f.writeErr
+	}
+	if s, ok := f.states[missionID]; ok {
+		s.RocketSetting = setting
+	}
+	return nil
+}
+
+func (f *fakeDB) UpdateSystemStatus(missionID uint, status db.RocketStatus) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.writeErr != nil {
+		return f.writeErr
+	}
+	if s, ok := f.states[missionID]; ok {
+		s.RocketStatus = status
+	}
+	return nil
+}
+
+func (f *fakeDB) systemStatus(missionID uint) db.RocketStatus {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.states[missionID].RocketStatus
+}
+
+func (f *fakeDB) AddEvent(missionID uint, eventType db.EventType, value string, createdBy string) (*db.Event, error) {
+	return f.AddSubEvent(missionID, 0, eventType, value, createdBy)
+}
+
+func (f *fakeDB) AddInjectedEvent(missionID uint, eventType db.EventType, value string) (*db.Event, error) {
+	e, _ := f.AddEvent(missionID, eventType, value, "system")
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.events[e.ID-1].Injected = true
+	e.Injected = true
+	return e, nil
+}
+
+func (f *fakeDB) AddSubEvent(missionID, parentID uint, eventType db.EventType, value string, createdBy string) (*db.Event, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	e := &db.Event{MissionID: missionID, CreatedBy: createdBy, PartOf: parentID, Type: eventType, Value: value}
+	e.ID = uint(len(f.events) + 1)
+	f.events = append(f.events, e)
+	event := *e
+	return &event, nil
+}
+
+func (f *fakeDB) UpdateEventStatus(id uint, status db.EventStatus) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if id > 0 && int(id) <= len(f.events) {
+		f.events[id-1].Status = status
+	}
+	return nil
+}
+
+func (f *fakeDB) UpdateEventDesc(id uint, desc string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if id > 0 && int(id) <= len(f.events) {
+		f.events[id-1].Desc = desc
+	}
+	return nil
+}
+
+func (f *fakeDB) GetRecentEvents(missionID uint, limit int) ([]*db.Event, error) {
+	if f.onRecentEvents != nil {
+		f.onRecentEvents()
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var events []*db.Event
+	for _, e := range f.events[max(len(f.events)-limit, 0):] {
+		event := *e
+		events = append(events, &event)
+	}
+	return events, nil
+}
+
+func (f *fakeDB) event(id uint) db.Event {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return *f.events[id-1]
+}
+
+func (f *fakeDB) AddAlarm(missionID uint, code string, level db.AlarmLevel, desc, raisedBy string, simTime float64) (*db.Alarm, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	a := &db.Alarm{MissionID: missionID, Code: code, Level: level, Desc: desc, RaisedBy: raisedBy, RaisedSimTime: simTime}
+	a.ID = uint(len(f.alarms) + 1)
+	f.alarms = append(f.alarms, a)
+	alarm := *a
+	return &alarm, nil
+}
+
+func (f *fakeDB) GetAlarm(id uint) (*db.Alarm, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if id == 0 || int(id) > len(f.alarms) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	alarm := *f.alarms[id-1]
+	return &alarm, nil
+}
+
+func (f *fakeDB) GetAlarmList(missionID uint, activeOnly bool) ([]*db.Alarm, error) {
+	f.mu.Lock()
+	var alarms []*db.Alarm
+	for _, a := range f.alarms {
+		if a.MissionID == missionID && (!activeOnly || a.Status != db.AlarmStatusCleared) {
+			alarm := *a
+			alarms = append(alarms, &alarm)
+		}
+	}
+	f.mu.Unlock()
+	// 查询结果返回前的延迟，期间其他协程写入的告警不在结果中
+	time.Sleep(f.alarmListDelay)
+	return alarms, nil
+}
+
+func (f *fakeDB) AcknowledgeAlarm(id uint, user string, simTime float64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	a := f.alarms[id-1]
+	a.Status, a.AcknowledgedBy = db.AlarmStatusAcknowledged, user
+	if a.RespondedSimTime == nil {
+		a.RespondedSimTime = &simTime
+	}
+	return nil
+}
+
+func (f *fakeDB) AddTelemetrySample(uint, db.RocketStatus) error { return nil }
+
+// newTestMission 使用 ManualClock 创建一个进行中的任务，任务创建者为 commander
+func newTestMission(t *testing.T) (*SingleMissionService, *fakeDB, *ManualClock) {
+	t.Helper()
+	return newTestMissionWithStatus(t, db.MissionStatusInProgress)
+}
+
+// newTestMissionWithStatus 同 newTestMission，任务的状态为 status
+func newTestMissionWithStatus(t *testing.T, status db.MissionStatus) (*SingleMissionService, *fakeDB, *ManualClock) {
+	t.Helper()
+	m := &db.Mission{Name: "test", CreatedBy: "commander", Status: status}
+	m.ID = 1
+	fdb := newFakeDB(m)
+	clock := NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	s, err := NewSingleMissionService(fdb, 1, WithClock(clock))
+	if err != nil {
+		t.Fatalf("failed to create mission: %v", err)
+	}
+	t.Cleanup(s.stop)
+	return s, fdb, clock
+}
+
+// waitFor 等待 cond 成立，ManualClock 只推进时间，后台协程的执行仍然是异步的
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// pendingTimers 返回 ManualClock 中等待触发的计时器数量
+func (c *ManualClock) pendingTimers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.waiters)
+}
@@ -0,0 +1,226 @@
+package mission
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/eli-yip/rocket-control/db"
+	"github.com/eli-yip/rocket-control/models"
+	"go.uber.org/zap"
+)
+
+const (
+	conditionPollInterval = 100 * time.Millisecond // wait_until 检查条件的间隔（模拟时间）
+	maxProgramDepth       = 8                      // 控制流步骤最多嵌套的层数
+	maxRepeatTimes        = 1000
+)
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// conditionFields 是条件可以引用的字段，名称与 RocketSetting、RocketStatus 的字段名一致，bool 字段取 1 或 0
+var conditionFields = map[string]func(setting *db.RocketSetting, status *db.RocketStatus) float64{
+	"Power":       func(s *db.RocketSetting, _ *db.RocketStatus) float64 { return boolToFloat(s.Power) },
+	"Comms":       func(s *db.RocketSetting, _ *db.RocketStatus) float64 { return boolToFloat(s.Comms) },
+	"Nav":         func(s *db.RocketSetting, _ *db.RocketStatus) float64 { return boolToFloat(s.Nav) },
+	"Life":        func(s *db.RocketSetting, _ *db.RocketStatus) float64 { return boolToFloat(s.Life) },
+	"Thrust":      func(s *db.RocketSetting, _ *db.RocketStatus) float64 { return s.Thrust },
+	"Altitude":    func(s *db.RocketSetting, _ *db.RocketStatus) float64 { return s.Altitude },
+	"Fuel":        func(s *db.RocketSetting, _ *db.RocketStatus) float64 { return s.Fuel },
+	"Speed":       func(s *db.RocketSetting, _ *db.RocketStatus) float64 { return s.Speed },
+	"Temperature": func(s *db.RocketSetting, _ *db.RocketStatus) float64 { return s.Temperature },
+	"Stabilizer":  func(s *db.RocketSetting, _ *db.RocketStatus) float64 { return s.Stabilizer },
+	"Oxygen":      func(s *db.RocketSetting, _ *db.RocketStatus) float64 { return s.Oxygen },
+	"Orbit":       func(s *db.RocketSetting, _ *db.RocketStatus) float64 { return s.Orbit },
+	"PowerLevel":  func(s *db.RocketSetting, _ *db.RocketStatus) float64 { return s.PowerLevel },
+	"Pressure":    func(s *db.RocketSetting, _ *db.RocketStatus) float64 { return s.Pressure },
+
+	"Launched":         func(_ *db.RocketSetting, s *db.RocketStatus) float64 { return boolToFloat(s.Launched) },
+	"HullLevel":        func(_ *db.RocketSetting, s *db.RocketStatus) float64 { return s.HullLevel },
+	"FuelLevel":        func(_ *db.RocketSetting, s *db.RocketStatus) float64 { return s.FuelLevel },
+	"OxygenLevel":      func(_ *db.RocketSetting, s *db.RocketStatus) float64 { return s.OxygenLevel },
+	"TemperatureLevel": func(_ *db.RocketSetting, s *db.RocketStatus) float64 { return s.TemperatureLevel },
+	"PressureLevel":    func(_ *db.RocketSetting, s *db.RocketStatus) float64 { return s.PressureLevel },
+	"AltitudeLevel":    func(_ *db.RocketSetting, s *db.RocketStatus) float64 { return s.AltitudeLevel },
+	"VelocityLevel":    func(_ *db.RocketSetting, s *db.RocketStatus) float64 { return s.VelocityLevel },
+}
+
+func isControlStep(t db.EventType) bool {
+	return t == db.EventTypeWaitUntil || t == db.EventTypeRepeat || t == db.EventTypeIf
+}
+
+func validateCondition(c *db.StepCondition) error {
+	if c == nil {
+		return fmt.Errorf("%w: condition is required", ErrInvalidProgramStep)
+	}
+	if _, ok := conditionFields[c.Field]; !ok {
+		return fmt.Errorf("%w: unknown condition field %q", ErrInvalidProgramStep, c.Field)
+	}
+	if !validConditionOp(c.Op) {
+		return fmt.Errorf("%w: unknown condition operator %q", ErrInvalidProgramStep, c.Op)
+	}
+	return nil
+}
+
+func validConditionOp(op db.ConditionOp) bool {
+	switch op {
+	case db.ConditionOpLt, db.ConditionOpLe, db.ConditionOpGt, db.ConditionOpGe, db.ConditionOpEq, db.ConditionOpNe:
+		return true
+	}
+	return false
+}
+
+// validateControlStep 检查控制流步骤，嵌套的步骤递归检查
+func validateControlStep(step db.ProgramStep, depth int) error {
+	if depth >= maxProgramDepth {
+		return fmt.Errorf("%w: control flow nested deeper than %d", ErrInvalidProgramStep, maxProgramDepth)
+	}
+	switch step.EventType {
+	case db.EventTypeWaitUntil:
+		if step.Timeout < 0 {
+			return fmt.Errorf("%w: negative timeout %d", ErrInvalidProgramStep, step.Timeout)
+		}
+		return validateCondition(step.Condition)
+	case db.EventTypeRepeat:
+		if step.Times < 1 || step.Times > maxRepeatTimes {
+			return fmt.Errorf("%w: repeat times must be between 1 and %d", ErrInvalidProgramStep, maxRepeatTimes)
+		}
+		if len(step.Steps) == 0 {
+			return fmt.Errorf("%w: repeat has no steps", ErrInvalidProgramStep)
+		}
+		return validateSteps(step.Steps, depth+1)
+	case db.EventTypeIf:
+		if err := validateCondition(step.Condition); err != nil {
+			return err
+		}
+		if len(step.Steps) == 0 && len(step.Else) == 0 {
+			return fmt.Errorf("%w: if has no steps", ErrInvalidProgramStep)
+		}
+		if err := validateSteps(step.Steps, depth+1); err != nil {
+			return err
+		}
+		if err := validateSteps(step.Else, depth+1); err != nil {
+			return fmt.Errorf("else: %w", err)
+		}
+	}
+	return nil
+}
+
+// describeControlStep 生成控制流子事件的 Value，用于在 Terminal 上展示
+func describeControlStep(step db.ProgramStep) string {
+	switch step.EventType {
+	case db.EventTypeRepeat:
+		return strconv.Itoa(step.Times)
+	case db.EventTypeWaitUntil, db.EventTypeIf:
+		if c := step.Condition; c != nil {
+			return fmt.Sprintf("%s %s %g", c.Field, c.Op, c.Value)
+		}
+	}
+	return step.Value
+}
+
+// checkCondition 用当前的 RocketSetting、RocketStatus 计算条件
+func (s *SingleMissionService) checkCondition(c *db.StepCondition) bool {
+	field, ok := conditionFields[c.Field]
+	if !ok {
+		return false
+	}
+	s.lock.Lock()
+	v := field(s.settings, s.status)
+	s.lock.Unlock()
+	return compare(v, c.Op, c.Value)
+}
+
+// compare 计算 v op target
+func compare(v float64, op db.ConditionOp, target float64) bool {
+	switch op {
+	case db.ConditionOpLt:
+		return v < target
+	case db.ConditionOpLe:
+		return v <= target
+	case db.ConditionOpGt:
+		return v > target
+	case db.ConditionOpGe:
+		return v >= target
+	case db.ConditionOpEq:
+		return v == target
+	case db.ConditionOpNe:
+		return v != target
+	}
+	return false
+}
+
+// runControlStep 创建控制流子事件并执行，嵌套的步骤作为它的子事件，返回该步骤的最终状态
+func (s *SingleMissionService) runControlStep(ctx context.Context, event models.Event, step db.ProgramStep, logger *zap.Logger) db.EventStatus {
+	value := describeControlStep(step)
+	sub, err := s.db.AddSubEvent(s.info.ID, event.ID, step.EventType, value, event.CreatedBy)
+	if err != nil {
+		logger.Error("failed to add subevent", zap.Error(err))
+		s.broadcast(models.Event{
+			EventType: step.EventType,
+			Status:    db.EventStatusFailed,
+			Value:     value,
+			CreatedBy: event.CreatedBy,
+			Injected:  event.Injected,
+		})
+		return db.EventStatusFailed
+	}
+	subEvent := models.Event{
+		ID:        sub.ID,
+		EventType: step.EventType,
+		Status:    db.EventStatusInProgress,
+		Value:     value,
+		CreatedBy: event.CreatedBy,
+		Injected:  event.Injected,
+	}
+	_ = s.db.UpdateEventStatus(subEvent.ID, db.EventStatusInProgress)
+	s.broadcast(subEvent)
+	logger = logger.With(zap.Uint("sub_e_id", subEvent.ID))
+
+	status := db.EventStatusCompleted
+	switch step.EventType {
+	case db.EventTypeWaitUntil:
+		status = s.waitUntil(ctx, step.Condition, time.Duration(step.Timeout)*time.Millisecond)
+		if status == db.EventStatusFailed {
+			s.failEvent(subEvent, fmt.Sprintf("timeout waiting for %s", value))
+			return status
+		}
+	case db.EventTypeRepeat:
+		for i := 0; i < step.Times && status == db.EventStatusCompleted; i++ {
+			status = s.runSteps(ctx, subEvent, step.Steps, logger)
+		}
+	case db.EventTypeIf:
+		branch := step.Else
+		if s.checkCondition(step.Condition) {
+			branch = step.Steps
+		}
+		status = s.runSteps(ctx, subEvent, branch, logger)
+	}
+	s.finishComplexEvent(subEvent, status)
+	return status
+}
+
+// waitUntil 等待条件成立，timeout 为 0 时一直等待，超时返回失败。
+// 只使用 clock.After 和 clock.Now，dry-run 的虚拟时钟也可以驱动。
+func (s *SingleMissionService) waitUntil(ctx context.Context, c *db.StepCondition, timeout time.Duration) db.EventStatus {
+	start := s.clock.Now()
+	for !s.checkCondition(c) {
+		if timeout > 0 && s.clock.Now().Sub(start) >= timeout {
+			return db.EventStatusFailed
+		}
+		select {
+		case <-ctx.Done():
+			return db.EventStatusCancelled
+		case <-s.done:
+			return db.EventStatusCancelled
+		case <-s.clock.After(conditionPollInterval):
+		}
+	}
+	return db.EventStatusCompleted
+}
@@ -0,0 +1,64 @@
+package mission
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/eli-yip/rocket-control/db"
+	"github.com/eli-yip/rocket-control/models"
+	"go.uber.org/zap"
+)
+
+var ErrInjectionFailed = errors.New("injection failed")
+
+// ValidateInjection 检查教员注入的状态变化，只能注入设置、状态变化和开关类事件
+func ValidateInjection(eventType db.EventType, value string) error {
+	if !settingStepTypes[eventType] && !triggerStepTypes[eventType] {
+		return fmt.Errorf("%w: event type %q can not be injected", ErrInvalidProgramStep, eventType)
+	}
+	return validateProgramStep(db.ProgramStep{EventType: eventType, Value: value}, 0)
+}
+
+// InjectAccident 立即发生指定的事故，和随机事故一样由 system 发起，返回事故父事件 ID。
+// 注入的事故只记录为教员可见的事件，Client 只能看到它的效果。
+func (s *SingleMissionService) InjectAccident(a *db.Accident) (uint, error) {
+	if !s.running() {
+		return 0, ErrMissionNotFound
+	}
+	steps, err := a.ProgramSteps()
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse accident steps: %w", err)
+	}
+	s.logger.Info("injecting accident", zap.String("accident", a.Name))
+	id := s.scheduleAccident(a, steps, true)
+	if id == 0 {
+		return 0, ErrInjectionFailed
+	}
+	return id, nil
+}
+
+// InjectStatus 注入一个由 system 发起的状态变化事件，返回事件 ID。
+// 事件和客户端操作一样经过重排窗口进入事件队列，Client 只能看到它的效果。
+func (s *SingleMissionService) InjectStatus(eventType db.EventType, value string) (uint, error) {
+	if err := ValidateInjection(eventType, value); err != nil {
+		return 0, err
+	}
+	if !s.running() {
+		return 0, ErrMissionNotFound
+	}
+	e, err := s.db.AddInjectedEvent(s.info.ID, eventType, value)
+	if err != nil {
+		return 0, fmt.Errorf("failed to add event: %w", err)
+	}
+	s.logger.Info("injecting status event", zap.String("event_type", string(eventType)), zap.String("value", value))
+
+	s.SubmitAction(models.Event{
+		ID:        e.ID,
+		EventType: eventType,
+		Status:    db.EventStatusPending,
+		Value:     value,
+		CreatedBy: "system",
+		Injected:  true,
+	})
+	return e.ID, nil
+}
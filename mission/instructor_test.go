@@ -0,0 +1,59 @@
+package mission
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/eli-yip/rocket-control/db"
+	"github.com/eli-yip/rocket-control/models"
+)
+
+func TestInjectStatusShowsOnlyEffects(t *testing.T) {
+	s, fdb, clock := newTestMission(t)
+
+	if _, err := s.InjectStatus(db.EventTypeThrust, "80"); !errors.Is(err, ErrMissionNotFound) {
+		t.Fatalf("injection into a stopped mission returned %v", err)
+	}
+
+	ch := make(chan models.WsMessage, eventBufferSize)
+	s.membersLock.Lock()
+	s.members["trainee"] = ch
+	s.started = true
+	s.membersLock.Unlock()
+
+	id, err := s.InjectStatus(db.EventTypeThrust, "80")
+	if err != nil {
+		t.Fatalf("failed to inject: %v", err)
+	}
+	if !fdb.event(id).Injected {
+		t.Fatal("injected event is not marked as injected")
+	}
+
+	// 注入的事件和客户端操作一样经过重排窗口
+	released := s.actions.release(clock.Now().Add(reorderMaxHold))
+	if len(released) != 1 || released[0].ID != id || !released[0].Injected {
+		t.Fatalf("released %+v", released)
+	}
+	s.processNormalEvent(released[0])
+
+	if s.settings.Thrust != 80 {
+		t.Fatalf("thrust %v after injection", s.settings.Thrust)
+	}
+	if got := fdb.event(id).Status; got != db.EventStatusCompleted {
+		t.Fatalf("injected event status %v", got)
+	}
+	close(ch)
+	var effects int
+	for m := range ch {
+		if m.CreatedBy != "system" || m.Msg != fmt.Sprintf("event %d processed", 0) {
+			t.Errorf("trainee received %+v", m)
+		}
+		if m.Action.Type == db.EventTypeThrust && m.Action.Value == "80" && m.Status == db.EventStatusCompleted {
+			effects++
+		}
+	}
+	if effects != 1 {
+		t.Errorf("trainee received %d thrust effects, want 1", effects)
+	}
+}
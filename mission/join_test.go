@@ -0,0 +1,42 @@
+package mission
+
+import (
+	"testing"
+
+	"github.com/eli-yip/rocket-control/db"
+	"github.com/eli-yip/rocket-control/models"
+)
+
+func TestJoinReplaysWithoutHoldingLocks(t *testing.T) {
+	s, fdb, _ := newTestMission(t)
+	thrust := addTestEvent(t, fdb, db.EventTypeThrust, "50", "commander")
+	comms := addTestEvent(t, fdb, db.EventTypeTriggerComms, "false", "commander")
+
+	fdb.onRecentEvents = func() {
+		if !s.lock.TryLock() {
+			t.Error("replay queried while holding lock")
+			return
+		}
+		s.lock.Unlock()
+		if !s.membersLock.TryLock() {
+			t.Error("replay queried while holding membersLock")
+			return
+		}
+		s.membersLock.Unlock()
+	}
+
+	ch, err := s.JoinMission("trainee")
+	if err != nil {
+		t.Fatalf("failed to join mission: %v", err)
+	}
+
+	if msg := <-ch; msg.Snapshot == nil {
+		t.Fatalf("first message %+v is not a snapshot", msg)
+	}
+	for _, want := range []models.Event{thrust, comms} {
+		msg := <-ch
+		if !msg.Replayed || msg.Action.Type != want.EventType || msg.Action.Value != want.Value {
+			t.Fatalf("replayed %+v, want %s %s", msg, want.EventType, want.Value)
+		}
+	}
+}
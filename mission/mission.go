@@ -2,79 +2,194 @@ package mission
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"math"
-	"math/rand/v2"
+	"sort"
 	"strconv"
 	"sync"
 	"time"
 
+	"github.com/eli-yip/rocket-control/config"
 	"github.com/eli-yip/rocket-control/db"
 	"github.com/eli-yip/rocket-control/log"
 	"github.com/eli-yip/rocket-control/models"
 
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
+// missionDB 是 SingleMissionService 运行时使用的数据库操作，db.Iface 实现了这些操作。
+// dry-run 使用的 dryRunDB 需要显式实现每一个方法，新增的写操作不会意外写入真实数据库。
+type missionDB interface {
+	GetAccidentByName(name string) (*db.Accident, error)
+	GetAccidentList() ([]*db.Accident, error)
+	GetCustomProgram(id uint) (*db.CustomProgram, error)
+	GetPreset(id uint) (*db.SystemPreset, error)
+	GetScenario(id uint) (*db.Scenario, error)
+
+	UpdateMissionStatus(id uint, status db.MissionStatus) error
+	StartMission(id uint, at time.Time) error
+	EndMission(id uint, status db.MissionStatus, result *db.MissionResult, at time.Time) error
+	UpdateSystemSetting(missionID uint, setting db.RocketSetting) error
+	UpdateSystemStatus(missionID uint, status db.RocketStatus) error
+	AddTelemetrySample(missionID uint, status db.RocketStatus) error
+
+	AddEvent(missionID uint, eventType db.EventType, value string, createdBy string) (*db.Event, error)
+	AddInjectedEvent(missionID uint, eventType db.EventType, value string) (*db.Event, error)
+	AddSubEvent(missionID, parentID uint, eventType db.EventType, value string, createdBy string) (*db.Event, error)
+	UpdateEventStatus(id uint, status db.EventStatus) error
+	UpdateEventDesc(id uint, desc string) error
+	GetRecentEvents(missionID uint, limit int) ([]*db.Event, error)
+	GetEventList(missionID uint, filter db.EventFilter) ([]*db.Event, error)
+
+	CreateDiagnostic(missionID uint, createdBy, desc string, result any) (*db.Diagnostic, error)
+
+	AddAlarm(missionID uint, code string, level db.AlarmLevel, desc, raisedBy string, simTime float64) (*db.Alarm, error)
+	GetAlarm(id uint) (*db.Alarm, error)
+	GetAlarmList(missionID uint, activeOnly bool) ([]*db.Alarm, error)
+	AcknowledgeAlarm(id uint, user string, simTime float64) error
+	ClearAlarm(id uint, user string, simTime float64) error
+}
+
 type SingleMissionService struct {
-	db               db.Iface
-	info             *db.Mission
-	settings         *db.RocketSetting
-	status           *db.RocketStatus
-	lock             sync.Mutex
-	members          map[string]chan models.WsMessage
-	events           chan models.Event
-	accidentEvent    chan models.Event
-	logger           *zap.Logger
-	done             chan struct{}
-	customCancelCtxs sync.Map // key: parent event id (uint), value: context.CancelFunc
-}
-
-const eventBufferSize = 1000
-
-func NewSingleMissionService(db db.Iface, missionID uint) (sms *SingleMissionService, err error) {
-	mission, err := db.GetMission(missionID)
+	db            missionDB
+	info          *db.Mission
+	settings      *db.RocketSetting
+	status        *db.RocketStatus
+	physics       PhysicsModel
+	clock         Clock
+	lock          sync.Mutex
+	membersLock   sync.RWMutex // 保护 members，broadcast 可能在持有 lock 时调用
+	alarmLock     sync.Mutex   // 保证检查和触发告警是原子的，需要在 lock 之前获取
+	endLock       sync.Mutex   // 串行化结束任务，需要在 lock 之前获取
+	stopOnce      sync.Once
+	members       map[string]chan models.WsMessage
+	events        chan models.Event
+	actions       *reorderBuffer // 客户端操作的重排窗口
+	accidentEvent chan accidentTask
+	logger        *zap.Logger
+	done          chan struct{} // 关闭时所有后台协程退出
+	startOnce     sync.Once
+	started       bool     // 后台协程已经启动，受 membersLock 保护
+	pinned        bool     // 由调度器启动的任务常驻内存，没有成员时也继续运行，受 membersLock 保护
+	programs      sync.Map // key: parent event id (uint), value: *runningProgram
+
+	statusBeforePause db.MissionStatus // 暂停前的任务状态，恢复时还原
+	dryRun            bool             // dry-run 时后台任务同步执行，保证时间线是确定的
+
+	scenario     *db.Scenario // 训练剧本，为空表示没有剧本
+	scenarioNext int          // 下一个待注入的剧本事件
+
+	nextSample float64   // 下一次记录遥测采样的任务经过时间（秒）
+	lastStep   time.Time // 上一次推进状态时的模拟时间
+	warned     int       // 已经发送的结束提醒数量，任务结束后随任务从内存中移除
+}
+
+const (
+	eventBufferSize    = 1000
+	accidentBufferSize = 10
+)
+
+type SingleMissionOptFunc func(s *SingleMissionService)
+
+// WithClock 使用指定的时钟驱动任务，例如在测试中使用 ManualClock
+func WithClock(clock Clock) SingleMissionOptFunc {
+	return func(s *SingleMissionService) { s.clock = clock }
+}
+
+func NewSingleMissionService(dbService db.Iface, missionID uint, opts ...SingleMissionOptFunc) (sms *SingleMissionService, err error) {
+	mission, err := dbService.GetMission(missionID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrMissionNotFound
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get mission: %w", err)
 	}
 
-	systemState, err := db.GetSystemState(missionID)
+	systemState, err := dbService.GetSystemState(missionID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// 通过 REST API 新建的任务还没有 SystemState，使用任务的初始状态创建
+		setting, status := mission.InitialState()
+		systemState, err = dbService.AddSystemState(missionID, setting, status)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get system state: %w", err)
 	}
 
+	normalizePhase(&systemState.RocketStatus)
+
+	physics, err := GetPhysicsModel(mission.PhysicsModel)
+	if err != nil {
+		return nil, err
+	}
+
 	sms = &SingleMissionService{
-		db:       db,
-		info:     mission,
-		settings: &systemState.RocketSetting,
-		status:   &systemState.RocketStatus,
-		lock:     sync.Mutex{},
-		members:  make(map[string]chan models.WsMessage),
-		events:   make(chan models.Event, eventBufferSize),
-		logger:   log.DefaultLogger.With(zap.Uint("mission", mission.ID)),
+		db:            dbService,
+		info:          mission,
+		settings:      &systemState.RocketSetting,
+		status:        &systemState.RocketStatus,
+		physics:       physics,
+		lock:          sync.Mutex{},
+		members:       make(map[string]chan models.WsMessage),
+		events:        make(chan models.Event, eventBufferSize),
+		actions:       newReorderBuffer(),
+		accidentEvent: make(chan accidentTask, accidentBufferSize),
+		logger:        log.DefaultLogger.With(zap.Uint("mission", mission.ID)),
+		done:          make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(sms)
+	}
+	if sms.clock == nil {
+		sms.clock = NewScaledClock()
+	}
+	sms.loadScenario()
+	if mission.Status == db.MissionStatusPaused {
+		// 任务在暂停时被回收，重新加载后保持暂停
+		sms.clock.Pause()
+		sms.statusBeforePause = db.MissionStatusInProgress
 	}
 
 	return sms, nil
 }
 
+// JoinMission 加入任务，返回的 channel 中第一条消息为任务的完整快照
 func (s *SingleMissionService) JoinMission(user string) (<-chan models.WsMessage, error) {
+	// 查询数据库不需要持有锁，告警和回放的事件在加锁之前读取。
+	// 读取之后、加锁之前发生的事件不会出现在回放中，但它们的效果已经包含在快照中
+	alarms, err := s.db.GetAlarmList(s.info.ID, true)
+	if err != nil {
+		s.logger.Error("failed to get active alarms", zap.Error(err))
+		alarms = []*db.Alarm{}
+	}
+	replay := s.replayEvents()
+
+	// 先持有 lock 再持有 membersLock，与持有 lock 时 broadcast 的顺序一致；
+	// 两把锁都持有时不会有新的广播，快照之后的变化都会出现在 channel 中。
+	// 持有锁时只向 channel 发送消息，回放的数量小于 channel 的容量，不会阻塞
 	s.lock.Lock()
 	defer s.lock.Unlock()
+	s.membersLock.Lock()
+	defer s.membersLock.Unlock()
 
+	if s.stoppedLocked() {
+		return nil, errMissionStopped
+	}
 	if _, exists := s.members[user]; exists {
 		return nil, fmt.Errorf("user %s already joined", user)
 	}
 
 	ch := make(chan models.WsMessage, eventBufferSize)
 	s.members[user] = ch
+	s.actions.reset(user)
+	ch <- s.snapshotLocked(alarms).ToWsMessage()
+	for _, e := range replay {
+		ch <- models.ReplayWsMessage(e)
+	}
 
 	if len(s.members) == 1 {
 		s.logger.Info("first user joined, starting mission service")
-		go s.process()
-		go s.adjustStatus()
-		go s.telemetry()
-		go s.accident()
-		go s.processAccident()
+		s.start()
 	}
 
 	joinEvent := models.Event{
@@ -89,8 +204,8 @@ func (s *SingleMissionService) JoinMission(user string) (<-chan models.WsMessage
 }
 
 func (s *SingleMissionService) LeaveMission(user string) (err error) {
-	s.lock.Lock()
-	defer s.lock.Unlock()
+	s.membersLock.Lock()
+	defer s.membersLock.Unlock()
 
 	if _, exists := s.members[user]; !exists {
 		return fmt.Errorf("user %s not found", user)
@@ -102,22 +217,132 @@ func (s *SingleMissionService) LeaveMission(user string) (err error) {
 		Value:     user,
 	}
 
-	go s.AddEvent(leaveEvent)
-
 	close(s.members[user])
 	delete(s.members, user)
+	s.actions.reset(user)
 
-	if len(s.members) == 0 {
+	if len(s.members) == 0 && !s.pinned {
 		s.logger.Info("all users left, stopping mission service")
-		s.done <- struct{}{}
+		s.stop()
+		// process 协程已经退出，直接记录离开事件
+		go s.recordEvent(leaveEvent, db.EventStatusCompleted)
+		return nil
 	}
 
+	go s.AddEvent(leaveEvent)
+
 	return nil
 }
 
+// snapshotLocked 生成任务的完整快照，调用者需要持有 lock 和 membersLock
+func (s *SingleMissionService) snapshotLocked(alarms []*db.Alarm) *models.Snapshot {
+	members := make([]string, 0, len(s.members))
+	for member := range s.members {
+		members = append(members, member)
+	}
+	sort.Strings(members)
+
+	info := *s.info
+	return &models.Snapshot{
+		Mission:  &info,
+		Setting:  *s.settings,
+		Status:   *s.status,
+		Programs: s.runningPrograms(),
+		Members:  members,
+		Alarms:   alarms,
+		SimTime:  s.clock.Now(),
+		SimSpeed: s.clock.Speed(),
+		Paused:   s.clock.Paused(),
+	}
+}
+
+const (
+	defaultReplayEvents = 100
+	maxReplayEvents     = eventBufferSize / 2 // 为回放之后的实时消息保留 channel 空间
+)
+
+// replayEvents 按原始顺序返回加入任务时需要回放的最近历史事件
+func (s *SingleMissionService) replayEvents() []*db.Event {
+	limit := config.C.Mission.ReplayEvents
+	if limit == 0 {
+		limit = defaultReplayEvents
+	}
+	if limit < 0 {
+		return nil
+	}
+	limit = min(limit, maxReplayEvents)
+
+	events, err := s.db.GetRecentEvents(s.info.ID, limit)
+	if err != nil {
+		s.logger.Error("failed to get recent events for replay", zap.Error(err))
+		return nil
+	}
+	return events
+}
+
+// start 启动后台协程，只会启动一次，调用者需要持有 membersLock
+func (s *SingleMissionService) start() {
+	s.startOnce.Do(func() {
+		s.started = true
+		go s.process()
+		go s.reorder()
+		go s.adjustStatus()
+		go s.telemetry()
+		go s.accident()
+		go s.processAccident()
+	})
+}
+
+// stop 停止所有后台协程，重复调用时不做任何事。
+// 调用者需要持有 membersLock，这样加入任务时可以检查任务是否已经停止
+func (s *SingleMissionService) stop() {
+	s.stopOnce.Do(func() {
+		close(s.done)
+		s.clock.Stop()
+	})
+}
+
+// stopped 返回任务是否已经停止，停止的任务不能再加入，需要从内存中移除
+func (s *SingleMissionService) stopped() bool {
+	s.membersLock.RLock()
+	defer s.membersLock.RUnlock()
+	return s.stoppedLocked()
+}
+
+// stoppedLocked 同 stopped，调用者需要持有 membersLock
+func (s *SingleMissionService) stoppedLocked() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// running 返回后台协程是否正在运行，只有运行中的任务会处理事件队列
+func (s *SingleMissionService) running() bool {
+	s.membersLock.RLock()
+	defer s.membersLock.RUnlock()
+	return s.started && !s.stoppedLocked()
+}
+
+// MemberCount 返回当前在线的成员数量
+func (s *SingleMissionService) MemberCount() int {
+	s.membersLock.RLock()
+	defer s.membersLock.RUnlock()
+	return len(s.members)
+}
+
+// Idle 返回任务是否已经没有成员并且没有被调度器固定，此时任务应该从内存中移除
+func (s *SingleMissionService) Idle() bool {
+	s.membersLock.RLock()
+	defer s.membersLock.RUnlock()
+	return len(s.members) == 0 && !s.pinned
+}
+
 func (s *SingleMissionService) GetCommChannel(user string) (<-chan models.WsMessage, error) {
-	s.lock.Lock()
-	defer s.lock.Unlock()
+	s.membersLock.RLock()
+	defer s.membersLock.RUnlock()
 
 	ch, exists := s.members[user]
 	if !exists {
@@ -128,6 +353,11 @@ func (s *SingleMissionService) GetCommChannel(user string) (<-chan models.WsMess
 
 func (s *SingleMissionService) AddEvent(event models.Event) {
 	event.Status = db.EventStatusPending
+	if event.ID != 0 {
+		// 已经记录的事件（教员注入）直接进入队列
+		s.events <- event
+		return
+	}
 	e, err := s.db.AddEvent(s.info.ID, event.EventType, event.Value, event.CreatedBy)
 	if err != nil {
 		s.logger.Error("failed to add event", zap.Error(err))
@@ -145,8 +375,27 @@ func (s *SingleMissionService) AddEvent(event models.Event) {
 	s.events <- event
 }
 
+// recordEvent 只在数据库中记录事件，不进入事件队列
+func (s *SingleMissionService) recordEvent(event models.Event, status db.EventStatus) {
+	e, err := s.db.AddEvent(s.info.ID, event.EventType, event.Value, event.CreatedBy)
+	if err != nil {
+		s.logger.Error("failed to add event", zap.Error(err))
+		return
+	}
+	_ = s.db.UpdateEventStatus(e.ID, status)
+}
+
+// spawn 在后台执行 f，dry-run 时同步执行
+func (s *SingleMissionService) spawn(f func()) {
+	if s.dryRun {
+		f()
+		return
+	}
+	go f()
+}
+
+// process 依次处理事件队列，客户端操作的先后顺序已经由 reorder 确定
 func (s *SingleMissionService) process() {
-	// TODO: 记录前端发来事件的时间戳，在一定时间范围内重新计算事件先后再执行
 	for {
 		select {
 		case <-s.done:
@@ -165,111 +414,161 @@ func (s *SingleMissionService) process() {
 	}
 }
 
-// 完整实现自定义程序事件的逐步执行与取消
+// 完整实现自定义程序事件的逐步执行与取消，event.Value 为自定义程序 ID
 func (s *SingleMissionService) processComplexEvent(event models.Event) {
 	logger := s.logger.With(zap.Uint("e_id", event.ID))
 	logger.Info("processing custom event", zap.String("event_type", string(event.EventType)), zap.String("value", event.Value))
 
+	program, steps, err := s.loadCustomProgram(event.Value)
+	if err != nil {
+		logger.Error("failed to load custom program", zap.Error(err))
+		s.failEvent(event, err.Error())
+		return
+	}
+	// 在父事件上记录运行的是哪个程序
+	_ = s.db.UpdateEventDesc(event.ID, program.Name)
+
 	// 创建可取消的 context
-	ctx, cancel := context.WithCancel(context.Background())
-	s.customCancelCtxs.Store(event.ID, cancel)
-	defer s.customCancelCtxs.Delete(event.ID)
+	ctx, stop := s.startProgram(event, program.Name)
+	defer stop()
 
 	// 广播开始
 	event.Status = db.EventStatusInProgress
 	s.broadcast(event)
 	_ = s.db.UpdateEventStatus(event.ID, db.EventStatusInProgress)
 
-	steps, err := s.db.GetCusomProgram(event.ID)
+	s.finishComplexEvent(event, s.runSteps(ctx, event, steps, logger))
+}
+
+// loadCustomProgram 根据事件 Value 中的程序 ID 加载自定义程序，返回的错误可以直接作为失败原因
+func (s *SingleMissionService) loadCustomProgram(val string) (*db.CustomProgram, db.ProgramSteps, error) {
+	id, err := strconv.ParseUint(val, 10, 64)
 	if err != nil {
-		event.Status = db.EventStatusFailed
-		s.logger.Error("failed to get custom program", zap.Error(err))
-		_ = s.db.UpdateEventStatus(event.ID, db.EventStatusFailed)
-		s.broadcast(event)
-		return
+		return nil, nil, fmt.Errorf("invalid custom program id %q", val)
 	}
+	program, err := s.db.GetCustomProgram(uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, fmt.Errorf("custom program %d not found", id)
+		}
+		s.logger.Error("failed to get custom program", zap.Uint64("program_id", id), zap.Error(err))
+		return nil, nil, fmt.Errorf("failed to load custom program %d", id)
+	}
+	if !s.info.AllowsProgram(program) {
+		return nil, nil, fmt.Errorf("custom program %d is not attached to this mission", id)
+	}
+	steps, err := program.ProgramSteps()
+	if err != nil {
+		s.logger.Error("failed to parse custom program steps", zap.Uint64("program_id", id), zap.Error(err))
+		return nil, nil, fmt.Errorf("custom program %d has invalid steps", id)
+	}
+	return program, steps, nil
+}
+
+// finishComplexEvent 记录并广播复合事件的最终状态
+func (s *SingleMissionService) finishComplexEvent(event models.Event, status db.EventStatus) {
+	event.Status = status
+	_ = s.db.UpdateEventStatus(event.ID, status)
+	s.broadcast(event)
+}
 
+// runSteps 将 steps 逐个作为 event 的子事件执行，返回父事件的最终状态。
+// 自定义程序和事故共用这一执行逻辑，控制流步骤会递归调用 runSteps。
+func (s *SingleMissionService) runSteps(ctx context.Context, event models.Event, steps db.ProgramSteps, logger *zap.Logger) db.EventStatus {
 	for idx, step := range steps {
+		s.setProgramProgress(event.ID, idx, len(steps))
 		select {
 		case <-ctx.Done():
-			logger.Info("custom program cancelled", zap.Int("step", idx))
-			event.Status = db.EventStatusCancelled
-			_ = s.db.UpdateEventStatus(event.ID, db.EventStatusCancelled)
-			s.broadcast(event)
-			return
+			logger.Info("complex event cancelled", zap.Int("step", idx))
+			return db.EventStatusCancelled
+		case <-s.done:
+			logger.Info("mission stopped, complex event cancelled", zap.Int("step", idx))
+			return db.EventStatusCancelled
 		default:
 		}
 
-		// 创建子事件
-		sub, err := s.db.AddSubEvent(s.info.ID, event.ID, step.EventType, step.Value, event.CreatedBy)
-		subEvent := models.Event{
-			ID:        sub.ID,
-			EventType: step.EventType,
-			Status:    db.EventStatusPending,
-			Value:     step.Value,
-			CreatedBy: event.CreatedBy,
-		}
-		if err != nil {
-			logger.Error("failed to add subevent", zap.Error(err))
-			subEvent.Status = db.EventStatusFailed
-			s.broadcast(subEvent)
-			// 主事件失败
-			event.Status = db.EventStatusFailed
-			_ = s.db.UpdateEventStatus(event.ID, db.EventStatusFailed)
-			s.broadcast(event)
-			return
-		}
-		subEvent.ID = sub.ID
-		subEvent.Status = db.EventStatusInProgress
-		_ = s.db.UpdateEventStatus(subEvent.ID, db.EventStatusInProgress)
-		s.broadcast(subEvent)
-
-		// 直接调用普通事件处理逻辑
-		// 检查子事件是否执行失败
-		failed := false
-		switch step.EventType {
-		case db.EventTypeThrust, db.EventTypeAlt, db.EventTypeFuel, db.EventTypeSpeed, db.EventTypeTemp,
-			db.EventTypeStabilizer, db.EventTypeOxygen, db.EventTypeOrbit, db.EventTypePowerLevel, db.EventTypePressure:
-			failed = !s.handleRocketSettingEvent(subEvent, logger)
-		case db.EventTypeTriggerPower, db.EventTypeTriggerComms, db.EventTypeTriggerNav, db.EventTypeTriggerLife:
-			failed = !s.handleRocketBoolSettingEvent(subEvent, logger)
-		case db.EventTypeHullChange, db.EventTypeFuelChange, db.EventTypeOxygenChange, db.EventTypeTempChange, db.EventTypePressureChange:
-			failed = !s.handleRocketStatusEvent(subEvent, logger)
-		default:
-			s.processNormalEvent(subEvent)
-			// 这里无法判断失败，假设成功
-		}
-		if failed {
-			subEvent.Status = db.EventStatusFailed
-			_ = s.db.UpdateEventStatus(subEvent.ID, db.EventStatusFailed)
-			s.broadcast(subEvent)
-			// 主事件失败
-			event.Status = db.EventStatusFailed
-			_ = s.db.UpdateEventStatus(event.ID, db.EventStatusFailed)
-			s.broadcast(event)
-			return
+		if isControlStep(step.EventType) {
+			if status := s.runControlStep(ctx, event, step, logger); status != db.EventStatusCompleted {
+				return status
+			}
+		} else if !s.runStep(event, step, logger) {
+			return db.EventStatusFailed
 		}
 
-		_ = s.db.UpdateEventStatus(subEvent.ID, db.EventStatusCompleted)
-		subEvent.Status = db.EventStatusCompleted
-		s.broadcast(subEvent)
-
 		// 等待 duration
 		select {
 		case <-ctx.Done():
-			logger.Info("custom program cancelled during wait", zap.Int("step", idx))
-			event.Status = db.EventStatusCancelled
-			_ = s.db.UpdateEventStatus(event.ID, db.EventStatusCancelled)
-			s.broadcast(event)
-			return
-		case <-time.After(time.Duration(step.Duration) * time.Millisecond):
+			logger.Info("complex event cancelled during wait", zap.Int("step", idx))
+			return db.EventStatusCancelled
+		case <-s.done:
+			logger.Info("mission stopped during wait", zap.Int("step", idx))
+			return db.EventStatusCancelled
+		case <-s.clock.After(time.Duration(step.Duration) * time.Millisecond):
 		}
 	}
 
 	// 全部完成
-	event.Status = db.EventStatusCompleted
-	_ = s.db.UpdateEventStatus(event.ID, db.EventStatusCompleted)
-	s.broadcast(event)
+	return db.EventStatusCompleted
+}
+
+// runStep 创建并执行一个子事件，返回子事件是否执行成功
+func (s *SingleMissionService) runStep(event models.Event, step db.ProgramStep, logger *zap.Logger) bool {
+	// 创建子事件
+	sub, err := s.db.AddSubEvent(s.info.ID, event.ID, step.EventType, step.Value, event.CreatedBy)
+	if err != nil {
+		logger.Error("failed to add subevent", zap.Error(err))
+		s.broadcast(models.Event{
+			EventType: step.EventType,
+			Status:    db.EventStatusFailed,
+			Value:     step.Value,
+			CreatedBy: event.CreatedBy,
+			Injected:  event.Injected,
+		})
+		return false
+	}
+	subEvent := models.Event{
+		ID:        sub.ID,
+		EventType: step.EventType,
+		Status:    db.EventStatusInProgress,
+		Value:     step.Value,
+		CreatedBy: event.CreatedBy,
+		Injected:  event.Injected,
+	}
+	_ = s.db.UpdateEventStatus(subEvent.ID, db.EventStatusInProgress)
+	s.broadcast(subEvent)
+
+	// 直接调用普通事件处理逻辑
+	// 检查子事件是否执行失败
+	failed := false
+	switch step.EventType {
+	case db.EventTypeThrust, db.EventTypeAlt, db.EventTypeFuel, db.EventTypeSpeed, db.EventTypeTemp,
+		db.EventTypeStabilizer, db.EventTypeOxygen, db.EventTypeOrbit, db.EventTypePowerLevel, db.EventTypePressure:
+		failed = !s.handleRocketSettingEvent(subEvent, logger)
+	case db.EventTypeTriggerPower, db.EventTypeTriggerComms, db.EventTypeTriggerNav, db.EventTypeTriggerLife:
+		failed = !s.handleRocketBoolSettingEvent(subEvent, logger)
+	case db.EventTypeHullChange, db.EventTypeFuelChange, db.EventTypeOxygenChange, db.EventTypeTempChange, db.EventTypePressureChange,
+		db.EventTypeAltitudeChange, db.EventTypeVelocityChange:
+		failed = !s.handleRocketStatusEvent(subEvent, logger)
+	case db.EventTypeTest:
+		// 命令类步骤同步执行，由处理函数记录并广播最终状态
+		return s.handleFlightCommand(subEvent, logger)
+	case db.EventTypeDiagnoseStart:
+		return s.doDiagnosticWithEvent(subEvent)
+	default:
+		// 保存时已经校验，这里拒绝旧数据中无法在程序中执行的步骤
+		logger.Warn("event type is not executable in a program", zap.String("event_type", string(step.EventType)))
+		s.failEvent(subEvent, fmt.Sprintf("%s cannot be used as a program step", step.EventType))
+		return false
+	}
+	if failed {
+		// 处理函数已经通过 failEvent 记录并广播失败
+		return false
+	}
+
+	_ = s.db.UpdateEventStatus(subEvent.ID, db.EventStatusCompleted)
+	subEvent.Status = db.EventStatusCompleted
+	s.broadcast(subEvent)
+	return true
 }
 
 // 取消自定义程序执行
@@ -280,10 +579,7 @@ func (s *SingleMissionService) cancelCustomProgram(val string) {
 		s.logger.Warn("invalid custom cancel value", zap.String("val", val), zap.Error(err))
 		return
 	}
-	cancelAny, ok := s.customCancelCtxs.Load(uint(id))
-	if ok {
-		cancel := cancelAny.(context.CancelFunc)
-		cancel()
+	if s.cancelProgram(uint(id)) {
 		s.logger.Info("custom program cancelled", zap.Uint64("event_id", id))
 	} else {
 		s.logger.Warn("no running custom program to cancel", zap.Uint64("event_id", id))
@@ -300,39 +596,31 @@ func (s *SingleMissionService) processNormalEvent(event models.Event) {
 		s.broadcast(event)
 		handled = true
 
-	case db.EventTypeLanuch:
+	case db.EventTypeErr:
+
+	case db.EventTypeLanuch, db.EventTypeAbort, db.EventTypeLand, db.EventTypeTest:
+		s.handleFlightCommand(event, logger)
 		handled = true
-		_ = s.db.UpdateEventStatus(event.ID, db.EventStatusInProgress)
-		for i := 10; i >= 1; i-- {
-			val := strconv.Itoa(i)
-			s.broadcast(models.Event{
-				ID:        event.ID,
-				EventType: db.EventTypeLanuch,
-				Status:    db.EventStatusInProgress,
-				Value:     val,
-				CreatedBy: event.CreatedBy,
-			})
-			time.Sleep(1 * time.Second)
-		}
-		// 发射成功，更新状态
-		s.lock.Lock()
-		s.status.Launched = true
-		_ = s.db.UpdateSystemStatus(s.info.ID, *s.status)
-		s.lock.Unlock()
-		_ = s.db.UpdateEventStatus(event.ID, db.EventStatusCompleted)
-		s.broadcast(event)
 
-	case db.EventTypeErr:
+	case db.EventTypeSimSpeed:
+		s.handleSimSpeedEvent(event, logger)
+		handled = true
 
-	case db.EventTypeAbort:
-	case db.EventTypeLand:
-	case db.EventTypeTest:
+	case db.EventTypePause, db.EventTypeResume:
+		s.handlePauseEvent(event, logger)
+		handled = true
 
 	case db.EventTypeDiagnoseStart:
 		handled = true
-		go s.doDiagnosticWithEvent(event)
+		s.spawn(func() { s.doDiagnosticWithEvent(event) })
 
-	case db.EventTypeAlarmClear:
+	case db.EventTypeAlarmSet, db.EventTypeAlarmAck, db.EventTypeAlarmClear:
+		s.handleAlarmEvent(event, logger)
+		handled = true
+
+	case db.EventTypeApplyPreset:
+		s.handlePresetEvent(event, logger)
+		handled = true
 
 	// Rocket setting events
 	case db.EventTypeThrust, db.EventTypeAlt, db.EventTypeFuel, db.EventTypeSpeed, db.EventTypeTemp,
@@ -345,7 +633,8 @@ func (s *SingleMissionService) processNormalEvent(event models.Event) {
 		handled = true
 
 	// 直接影响火箭状态的事件
-	case db.EventTypeHullChange, db.EventTypeFuelChange, db.EventTypeOxygenChange, db.EventTypeTempChange, db.EventTypePressureChange:
+	case db.EventTypeHullChange, db.EventTypeFuelChange, db.EventTypeOxygenChange, db.EventTypeTempChange, db.EventTypePressureChange,
+		db.EventTypeAltitudeChange, db.EventTypeVelocityChange:
 		s.handleRocketStatusEvent(event, logger)
 		handled = true
 	}
@@ -356,8 +645,8 @@ func (s *SingleMissionService) processNormalEvent(event models.Event) {
 	}
 }
 
-// doDiagnosticWithEvent: 执行诊断并广播结果
-func (s *SingleMissionService) doDiagnosticWithEvent(event models.Event) {
+// doDiagnosticWithEvent: 执行诊断并广播结果，返回诊断是否成功
+func (s *SingleMissionService) doDiagnosticWithEvent(event models.Event) bool {
 	s.logger.Info("starting diagnostic", zap.String("by", event.CreatedBy))
 	// 标记事件为进行中
 	_ = s.db.UpdateEventStatus(event.ID, db.EventStatusInProgress)
@@ -373,14 +662,12 @@ func (s *SingleMissionService) doDiagnosticWithEvent(event models.Event) {
 	diag, err := s.db.CreateDiagnostic(s.info.ID, event.CreatedBy, desc, result)
 	if err != nil {
 		s.logger.Error("failed to create diagnostic", zap.Error(err))
-		_ = s.db.UpdateEventStatus(event.ID, db.EventStatusFailed)
-		s.broadcast(models.Event{
+		s.failEvent(models.Event{
 			ID:        event.ID,
 			EventType: db.EventTypeDiagnoseStart,
-			Status:    db.EventStatusFailed,
 			CreatedBy: event.CreatedBy,
-		})
-		return
+		}, "failed to create diagnostic")
+		return false
 	}
 
 	// 广播诊断结果事件
@@ -393,6 +680,7 @@ func (s *SingleMissionService) doDiagnosticWithEvent(event models.Event) {
 	}
 	_ = s.db.UpdateEventStatus(event.ID, db.EventStatusCompleted)
 	s.broadcast(resultEvent)
+	return true
 }
 
 // doDiagnosticResult: 生成诊断结果和描述
@@ -414,19 +702,19 @@ func (s *SingleMissionService) doDiagnosticResult() (map[string]interface{}, str
 	}
 	// 简单诊断描述
 	desc := ""
-	if s.status.HullLevel < 20 {
+	if s.status.HullLevel < hullMin {
 		desc += "Hull integrity low. "
 	}
-	if s.status.FuelLevel < 10 {
+	if s.status.FuelLevel < fuelMin {
 		desc += "Fuel low. "
 	}
-	if s.status.OxygenLevel < 10 {
+	if s.status.OxygenLevel < oxygenMin {
 		desc += "Oxygen low. "
 	}
-	if s.status.TemperatureLevel > 90 {
+	if s.status.TemperatureLevel > tempMax {
 		desc += "Temperature high. "
 	}
-	if s.status.PressureLevel < 15 || s.status.PressureLevel > 95 {
+	if s.status.PressureLevel < pressureMin || s.status.PressureLevel > pressureMax {
 		desc += "Pressure abnormal. "
 	}
 	if desc == "" {
@@ -443,10 +731,10 @@ func (s *SingleMissionService) handleRocketSettingEvent(event models.Event, logg
 	val, err := parseEventValueToFloat(event.Value)
 	if err != nil {
 		logger.Warn("invalid value for rocket setting event", zap.String("value", event.Value), zap.Error(err))
-		_ = s.db.UpdateEventStatus(event.ID, db.EventStatusFailed)
-		s.broadcast(event)
+		s.failEvent(event, fmt.Sprintf("invalid value %q", event.Value))
 		return false
 	}
+	old := *s.settings
 
 	switch event.EventType {
 	case db.EventTypeThrust:
@@ -473,11 +761,16 @@ func (s *SingleMissionService) handleRocketSettingEvent(event models.Event, logg
 
 	if err := s.db.UpdateSystemSetting(s.info.ID, *s.settings); err != nil {
 		logger.Error("failed to update rocket settings in db", zap.Error(err))
-		_ = s.db.UpdateEventStatus(event.ID, db.EventStatusFailed)
-		s.broadcast(event)
+		*s.settings = old
+		s.failEvent(event, "failed to save rocket setting")
 		return false
 	}
 
+	// 上升阶段打开轨道控制即视为入轨
+	if event.EventType == db.EventTypeOrbit && val > 0 && s.status.Phase == db.FlightPhaseAscent {
+		_ = s.transitionLocked(db.FlightPhaseOrbit)
+	}
+
 	_ = s.db.UpdateEventStatus(event.ID, db.EventStatusCompleted)
 	s.broadcast(event)
 	return true
@@ -490,10 +783,10 @@ func (s *SingleMissionService) handleRocketBoolSettingEvent(event models.Event,
 	val, err := strconv.ParseBool(event.Value)
 	if err != nil {
 		logger.Warn("invalid value for rocket bool setting event", zap.String("value", event.Value), zap.Error(err))
-		_ = s.db.UpdateEventStatus(event.ID, db.EventStatusFailed)
-		s.broadcast(event)
+		s.failEvent(event, fmt.Sprintf("invalid value %q", event.Value))
 		return false
 	}
+	old := *s.settings
 
 	switch event.EventType {
 	case db.EventTypeTriggerPower:
@@ -508,8 +801,8 @@ func (s *SingleMissionService) handleRocketBoolSettingEvent(event models.Event,
 
 	if err := s.db.UpdateSystemSetting(s.info.ID, *s.settings); err != nil {
 		logger.Error("failed to update rocket bool settings in db", zap.Error(err))
-		_ = s.db.UpdateEventStatus(event.ID, db.EventStatusFailed)
-		s.broadcast(event)
+		*s.settings = old
+		s.failEvent(event, "failed to save rocket setting")
 		return false
 	}
 
@@ -525,10 +818,10 @@ func (s *SingleMissionService) handleRocketStatusEvent(event models.Event, logge
 	val, err := parseEventValueToFloat(event.Value)
 	if err != nil {
 		logger.Warn("invalid value for rocket status event", zap.String("value", event.Value), zap.Error(err))
-		_ = s.db.UpdateEventStatus(event.ID, db.EventStatusFailed)
-		s.broadcast(event)
+		s.failEvent(event, fmt.Sprintf("invalid value %q", event.Value))
 		return false
 	}
+	old := *s.status
 
 	switch event.EventType {
 	case db.EventTypeHullChange:
@@ -541,12 +834,17 @@ func (s *SingleMissionService) handleRocketStatusEvent(event models.Event, logge
 		s.status.TemperatureLevel = val
 	case db.EventTypePressureChange:
 		s.status.PressureLevel = val
+	case db.EventTypeAltitudeChange:
+		s.status.AltitudeLevel = val
+	case db.EventTypeVelocityChange:
+		s.status.VelocityLevel = val
 	}
+	s.status.TrackExtremes()
 
 	if err := s.db.UpdateSystemStatus(s.info.ID, *s.status); err != nil {
-		logger.Error("failed to update rocket settings in db", zap.Error(err))
-		_ = s.db.UpdateEventStatus(event.ID, db.EventStatusFailed)
-		s.broadcast(event)
+		logger.Error("failed to update rocket status in db", zap.Error(err))
+		*s.status = old
+		s.failEvent(event, "failed to save rocket status")
 		return false
 	}
 
@@ -560,7 +858,21 @@ func parseEventValueToFloat(val string) (float64, error) {
 	return strconv.ParseFloat(val, 64)
 }
 
+// broadcast 将事件发送给所有成员。教员注入的事件只发送生效的设置、状态变化和开关，
+// 不带事件 ID，和物理模型推进产生的状态变化一样，Client 无法分辨注入本身。
 func (s *SingleMissionService) broadcast(event models.Event) {
+	if event.Injected {
+		// 处理函数广播生效的事件时状态还没有更新（Pending），程序步骤完成后会再广播一次 Completed
+		if (event.Status != db.EventStatusPending && event.Status != db.EventStatusCompleted) ||
+			(!settingStepTypes[event.EventType] && !triggerStepTypes[event.EventType]) {
+			return
+		}
+		event = models.Event{EventType: event.EventType, Status: db.EventStatusCompleted, Value: event.Value, CreatedBy: "system"}
+	}
+
+	s.membersLock.RLock()
+	defer s.membersLock.RUnlock()
+
 	for id, ch := range s.members {
 		select {
 		case ch <- event.ToWsMessage("event processed"):
@@ -570,110 +882,32 @@ func (s *SingleMissionService) broadcast(event models.Event) {
 	}
 }
 
+const statusTickInterval = 1 * time.Second // 调整为 1 秒，便于观察
+
+const telemetrySampleInterval = 10 * time.Second // 飞行中记录遥测采样的模拟时间间隔
+
 func (s *SingleMissionService) adjustStatus() {
-	s.logger.Info("adjust status started")
+	s.logger.Info("adjust status started", zap.String("physics", s.physics.Name()))
 
-	ticker := time.NewTicker(1 * time.Second) // 调整为 1 秒，便于观察
+	ticker := s.clock.NewTicker(statusTickInterval)
 	defer ticker.Stop()
 
-	// 阈值定义
-	const (
-		hullMin     = 20.0
-		fuelMin     = 10.0
-		oxygenMin   = 10.0
-		tempMax     = 90.0
-		pressureMin = 15.0
-		pressureMax = 95.0
-	)
-
 	for {
 		select {
-		case <-ticker.C:
+		case <-ticker.C():
 			s.lock.Lock()
-			// 记录旧值用于判断是否突破阈值
-			oldStatus := *s.status
-
-			// 1. 根据 settings 调整 status
-			// HullLevel 缓慢下降
-			s.status.HullLevel -= 0.05
-			if s.status.HullLevel < 0 {
-				s.status.HullLevel = 0
-			}
-			// FuelLevel 消耗与 Thrust 相关
-			s.status.FuelLevel -= 0.1 + 0.2*s.settings.Thrust/100
-			if s.status.FuelLevel < 0 {
-				s.status.FuelLevel = 0
-			}
-			// OxygenLevel 消耗与 Life 支持和 Thrust 相关
-			oxygenRate := 0.05
-			if s.settings.Life {
-				oxygenRate += 0.05
-			}
-			oxygenRate += 0.1 * s.settings.Thrust / 100
-			s.status.OxygenLevel -= oxygenRate
-			if s.status.OxygenLevel < 0 {
-				s.status.OxygenLevel = 0
-			}
-			// 温度与 Thrust 和 PowerLevel 相关
-			tempDelta := 0.05*s.settings.Thrust + 0.03*s.settings.PowerLevel
-			s.status.TemperatureLevel += tempDelta - 0.1 // 有一定冷却
-			if s.status.TemperatureLevel < 0 {
-				s.status.TemperatureLevel = 0
-			}
-			// 压力与 Altitude 和 Fuel 相关
-			pressureDelta := 0.05*s.settings.Altitude - 0.03*s.settings.Fuel
-			s.status.PressureLevel += pressureDelta
-			if s.status.PressureLevel < 0 {
-				s.status.PressureLevel = 0
-			}
+			crossings := s.stepStatusLocked(statusTickInterval)
 
-			// 2. 写入数据库
-			if err := s.db.UpdateSystemStatus(s.info.ID, *s.status); err != nil {
-				s.logger.Error("failed to update rocket status in db", zap.Error(err))
-			}
-
-			// 3. 变化后发送 event 到前端
-			// 只要有变化就发送
-			statusEvents := []struct {
-				typ   db.EventType
-				val   float64
-				field string
-			}{
-				{db.EventTypeHullChange, s.status.HullLevel, "HullLevel"},
-				{db.EventTypeFuelChange, s.status.FuelLevel, "FuelLevel"},
-				{db.EventTypeOxygenChange, s.status.OxygenLevel, "OxygenLevel"},
-				{db.EventTypeTempChange, s.status.TemperatureLevel, "TemperatureLevel"},
-				{db.EventTypePressureChange, s.status.PressureLevel, "PressureLevel"},
-			}
-			for _, ev := range statusEvents {
-				event := models.Event{
-					EventType: ev.typ,
-					Value:     strconv.FormatFloat(ev.val, 'f', 2, 64),
-					CreatedBy: "system",
-				}
-				s.broadcast(event)
-			}
-
-			// 4. 阈值触发诊断（只要有一项突破阈值就触发）
-			triggerDiag := false
-			if oldStatus.HullLevel >= hullMin && s.status.HullLevel < hullMin {
-				triggerDiag = true
-			}
-			if oldStatus.FuelLevel >= fuelMin && s.status.FuelLevel < fuelMin {
-				triggerDiag = true
-			}
-			if oldStatus.OxygenLevel >= oxygenMin && s.status.OxygenLevel < oxygenMin {
-				triggerDiag = true
-			}
-			if oldStatus.TemperatureLevel <= tempMax && s.status.TemperatureLevel > tempMax {
-				triggerDiag = true
+			// 阈值触发诊断（只要有一项突破阈值就触发）和告警
+			if len(crossings) > 0 {
+				go s.doDiagnostic()
 			}
-			if (oldStatus.PressureLevel >= pressureMin && s.status.PressureLevel < pressureMin) ||
-				(oldStatus.PressureLevel <= pressureMax && s.status.PressureLevel > pressureMax) {
-				triggerDiag = true
+			for _, c := range crossings {
+				go s.raiseAlarm(c.code, c.level, c.desc, "system")
 			}
-			if triggerDiag {
-				go s.doDiagnostic()
+			// 同一时刻到期的剧本事件按剧本中的顺序依次注入
+			if due := s.dueScenarioEventsLocked(); len(due) > 0 {
+				go s.injectScenarioEvents(due)
 			}
 
 			s.lock.Unlock()
@@ -684,65 +918,146 @@ func (s *SingleMissionService) adjustStatus() {
 	}
 }
 
-func (s *SingleMissionService) telemetry() {
-	s.logger.Info("telemetry started")
+// sampleTelemetryLocked 在飞行中每经过 telemetrySampleInterval 记录一次遥测采样，调用者需要持有 s.lock
+func (s *SingleMissionService) sampleTelemetryLocked() {
+	if !inFlight(s.status.Phase) || s.status.ElapsedTime < s.nextSample {
+		return
+	}
+	s.nextSample = s.status.ElapsedTime + telemetrySampleInterval.Seconds()
+	if err := s.db.AddTelemetrySample(s.info.ID, *s.status); err != nil {
+		s.logger.Error("failed to add telemetry sample", zap.Error(err))
+	}
+}
 
-	ticker := time.NewTicker(500 * time.Microsecond)
-	defer ticker.Stop()
+// stepStatusLocked 使用物理模型将火箭状态推进 dt，写入数据库并广播，返回新突破的阈值。
+// 调用者需要持有 s.lock。
+func (s *SingleMissionService) stepStatusLocked(dt time.Duration) []thresholdCrossing {
+	// 记录旧值用于判断是否突破阈值
+	oldStatus := *s.status
 
-	for {
-		select {
-		case <-ticker.C:
-			s.logger.Info("telemetry", zap.Any("status", s.status), zap.Any("settings", s.settings))
-		case <-s.done:
-			s.logger.Info("telemetry stopped")
-			return
+	// 1. 根据 settings 调整 status
+	*s.status = s.physics.Step(*s.settings, *s.status, dt)
+	if inFlight(s.status.Phase) {
+		s.status.ElapsedTime += dt.Seconds()
+	}
+	if s.info.Status == db.MissionStatusInProgress {
+		s.status.MissionTime += dt.Seconds()
+	}
+	s.status.SimElapsed += dt.Seconds()
+	s.lastStep = s.clock.Now()
+	s.status.TrackExtremes()
+
+	// 2. 写入数据库
+	if err := s.db.UpdateSystemStatus(s.info.ID, *s.status); err != nil {
+		s.logger.Error("failed to update rocket status in db", zap.Error(err))
+	}
+	s.sampleTelemetryLocked()
+
+	// 3. 变化后发送 event 到前端
+	// 只要有变化就发送
+	statusEvents := []struct {
+		typ   db.EventType
+		val   float64
+		field string
+	}{
+		{db.EventTypeHullChange, s.status.HullLevel, "HullLevel"},
+		{db.EventTypeFuelChange, s.status.FuelLevel, "FuelLevel"},
+		{db.EventTypeOxygenChange, s.status.OxygenLevel, "OxygenLevel"},
+		{db.EventTypeTempChange, s.status.TemperatureLevel, "TemperatureLevel"},
+		{db.EventTypePressureChange, s.status.PressureLevel, "PressureLevel"},
+		{db.EventTypeAltitudeChange, s.status.AltitudeLevel, "AltitudeLevel"},
+		{db.EventTypeVelocityChange, s.status.VelocityLevel, "VelocityLevel"},
+	}
+	for _, ev := range statusEvents {
+		event := models.Event{
+			EventType: ev.typ,
+			Value:     strconv.FormatFloat(ev.val, 'f', 2, 64),
+			CreatedBy: "system",
 		}
+		s.broadcast(event)
 	}
+
+	return thresholdCrossings(oldStatus, *s.status)
 }
 
-const accidentTimeWindow = 5 * time.Minute
+const telemetryInterval = 500 * time.Millisecond
+
+func (s *SingleMissionService) telemetry() {
+	s.logger.Info("telemetry started")
 
-func (s *SingleMissionService) accident() {
-	ticker := time.NewTicker(accidentTimeWindow)
+	ticker := s.clock.NewTicker(telemetryInterval)
 	defer ticker.Stop()
 
 	for {
 		select {
-		case <-ticker.C:
-			successRate := s.settings.Stabilizer
-			if !shouldAccident(accidentTimeWindow, successRate) {
-				continue
-			}
-			s.logger.Info("accident occurred")
-			a, err := s.db.GetRandomAccident()
-			if err != nil {
-				s.logger.Error("failed to get random accident", zap.Error(err))
-				continue
-			}
-			for range a {
-				// TODO: 向 accidentCh 发送事故事件
-			}
+		case <-ticker.C():
+			s.lock.Lock()
+			status, settings := *s.status, *s.settings
+			s.lock.Unlock()
+			s.logger.Debug("telemetry", zap.Any("status", status), zap.Any("settings", settings))
 		case <-s.done:
-			s.logger.Info("accident check stopped")
+			s.logger.Info("telemetry stopped")
 			return
 		}
 	}
 }
 
-func (s *SingleMissionService) processAccident() {
-	// TODO: 处理事故事件，和 processNormalEvent 类似
+func (s *SingleMissionService) doDiagnostic() {
+	// TODO: 实现诊断逻辑
 }
 
-func shouldAccident(duration time.Duration, successRate float64) bool {
-	if successRate < 0 || successRate > 1 {
-		return false
+// handleSimSpeedEvent 设置模拟速度，只有任务指挥（任务创建者）可以设置
+func (s *SingleMissionService) handleSimSpeedEvent(event models.Event, logger *zap.Logger) {
+	if event.CreatedBy != s.info.CreatedBy {
+		s.failEvent(event, "only the mission commander can change simulation speed")
+		return
 	}
-	failureRate := 1.0 - successRate
-	effectiveFailureRate := 1.0 - math.Exp(-failureRate*float64(duration)/float64(accidentTimeWindow))
-	return rand.Float64() < effectiveFailureRate
+	speed, err := parseEventValueToFloat(event.Value)
+	if err != nil {
+		logger.Warn("invalid value for sim speed event", zap.String("value", event.Value), zap.Error(err))
+		s.failEvent(event, "invalid simulation speed")
+		return
+	}
+	if speed < MinSimSpeed || speed > MaxSimSpeed {
+		s.failEvent(event, fmt.Sprintf("simulation speed must be between %gx and %gx", MinSimSpeed, MaxSimSpeed))
+		return
+	}
+
+	s.clock.SetSpeed(speed)
+	logger.Info("simulation speed changed", zap.Float64("speed", speed))
+	s.finishComplexEvent(event, db.EventStatusCompleted)
 }
 
-func (s *SingleMissionService) doDiagnostic() {
-	// TODO: 实现诊断逻辑
+// handlePauseEvent 暂停或恢复任务。暂停期间模拟时间冻结，资源消耗、事故判定、
+// 自定义程序的步骤等待和发射倒计时都会停止，恢复后从暂停处继续。
+func (s *SingleMissionService) handlePauseEvent(event models.Event, logger *zap.Logger) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	pause := event.EventType == db.EventTypePause
+	if pause == s.clock.Paused() {
+		if pause {
+			s.failEvent(event, "mission already paused")
+		} else {
+			s.failEvent(event, "mission is not paused")
+		}
+		return
+	}
+
+	status := s.statusBeforePause
+	if pause {
+		s.statusBeforePause = s.info.Status
+		status = db.MissionStatusPaused
+		s.clock.Pause()
+	} else {
+		s.clock.Resume()
+	}
+	if err := s.db.UpdateMissionStatus(s.info.ID, status); err != nil {
+		logger.Error("failed to update mission status", zap.Error(err))
+	}
+	s.info.Status = status
+	logger.Info("mission pause state changed", zap.Bool("paused", pause), zap.Time("sim_time", s.clock.Now()))
+
+	_ = s.db.UpdateEventDesc(event.ID, "sim time "+s.clock.Now().Format(time.RFC3339))
+	s.finishComplexEvent(event, db.EventStatusCompleted)
 }
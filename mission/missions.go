@@ -3,6 +3,7 @@ package mission
 import (
 	"errors"
 	"sync"
+	"time"
 
 	"github.com/eli-yip/rocket-control/db"
 	"github.com/eli-yip/rocket-control/models"
@@ -15,48 +16,126 @@ func InitMissionService(db db.Iface) {
 }
 
 type MissionService struct {
-	db db.Iface
-	m  sync.Map
+	db      db.Iface
+	m       sync.Map               // key: mission id (uint), value: *SingleMissionService
+	lock    sync.Mutex             // 保护 loading，只在查找和写入 m 时持有，不在持有时访问数据库
+	loading map[uint]chan struct{} // 正在加载（或在数据库中结束）的任务，完成后 channel 关闭
 }
 
 func NewMissionService(db db.Iface) *MissionService {
-	return &MissionService{db: db}
+	return &MissionService{db: db, loading: make(map[uint]chan struct{})}
 }
 
 var (
 	ErrMissionAlreadyExists = errors.New("mission already exists")
 	ErrMissionNotFound      = errors.New("mission not found")
+	ErrMissionEnded         = errors.New("mission already ended")
+
+	errMissionStopped = errors.New("mission stopped") // 任务已经停止，需要从内存中移除后重新加载
 )
 
+// lookup 返回内存中的任务。任务不在内存中时占用该任务的加载位置并返回 release，
+// 调用者完成加载后调用 release（加载失败时传入 nil），同一任务的其他调用者会等待 release 之后重新查找
+func (ms *MissionService) lookup(id uint) (*SingleMissionService, func(*SingleMissionService)) {
+	for {
+		ms.lock.Lock()
+		if v, ok := ms.m.Load(id); ok {
+			ms.lock.Unlock()
+			return v.(*SingleMissionService), nil
+		}
+		if wait, ok := ms.loading[id]; ok {
+			ms.lock.Unlock()
+			<-wait
+			continue
+		}
+		done := make(chan struct{})
+		ms.loading[id] = done
+		ms.lock.Unlock()
+
+		return nil, func(sms *SingleMissionService) {
+			ms.lock.Lock()
+			delete(ms.loading, id)
+			if sms != nil {
+				ms.m.Store(id, sms)
+			}
+			ms.lock.Unlock()
+			close(done)
+		}
+	}
+}
+
+// load 返回内存中的任务，任务不在内存中时从数据库加载，同一任务只会加载一次
+func (ms *MissionService) load(id uint) (*SingleMissionService, error) {
+	sms, release := ms.lookup(id)
+	if release == nil {
+		return sms, nil
+	}
+	sms, err := NewSingleMissionService(ms.db, id)
+	if err != nil {
+		release(nil)
+		return nil, err
+	}
+	release(sms)
+	return sms, nil
+}
+
+// remove 将已经停止的任务从内存中移除，任务已经被重新加载时不做任何事
+func (ms *MissionService) remove(id uint, sms *SingleMissionService) {
+	ms.m.CompareAndDelete(id, sms)
+}
+
 func (ms *MissionService) AddMission(id uint) (err error) {
-	if _, ok := ms.m.Load(id); ok {
+	_, release := ms.lookup(id)
+	if release == nil {
 		return ErrMissionAlreadyExists
 	}
-
 	sms, err := NewSingleMissionService(ms.db, id)
 	if err != nil {
+		release(nil)
 		return err
 	}
-	ms.m.Store(id, sms)
+	release(sms)
 	return nil
 }
 
+// JoinMission 加入一个任务，任务不在内存中时从数据库加载。
+// 没有设置开始时间的任务在第一个成员加入时开始，之后由调度器按 Duration 结束
 func (ms *MissionService) JoinMission(id uint, user string) (<-chan models.WsMessage, error) {
-	v, ok := ms.m.Load(id)
-	if !ok {
-		return nil, ErrMissionNotFound
+	for {
+		sms, err := ms.load(id)
+		if err != nil {
+			return nil, err
+		}
+		var ch <-chan models.WsMessage
+		if sms.unscheduled() {
+			err = sms.pin(time.Now())
+		}
+		if err == nil {
+			ch, err = sms.JoinMission(user)
+		}
+		if errors.Is(err, errMissionStopped) {
+			// 最后一个成员刚刚离开，任务已经停止，移除后重新加载
+			ms.remove(id, sms)
+			continue
+		}
+		return ch, err
 	}
-	sms := v.(*SingleMissionService)
-	return sms.JoinMission(user)
 }
 
+// LeaveMission 离开一个任务，最后一个成员离开后任务从内存中移除，调度器启动的任务除外
 func (ms *MissionService) LeaveMission(id uint, user string) (err error) {
 	v, ok := ms.m.Load(id)
 	if !ok {
 		return ErrMissionNotFound
 	}
 	sms := v.(*SingleMissionService)
-	return sms.LeaveMission(user)
+	if err = sms.LeaveMission(user); err != nil {
+		return err
+	}
+	if sms.stopped() {
+		ms.remove(id, sms)
+	}
+	return nil
 }
 
 func (ms *MissionService) GetCommChannel(id uint, user string) (<-chan models.WsMessage, error) {
@@ -76,3 +155,31 @@ func (ms *MissionService) AddEvent(id uint, event models.Event) {
 	sms := v.(*SingleMissionService)
 	sms.AddEvent(event)
 }
+
+// SubmitAction 提交客户端发来的操作，操作会在重排窗口内按客户端时间戳和序号排序
+func (ms *MissionService) SubmitAction(id uint, event models.Event) {
+	v, ok := ms.m.Load(id)
+	if !ok {
+		return
+	}
+	sms := v.(*SingleMissionService)
+	sms.SubmitAction(event)
+}
+
+// InjectAccident 向运行中的任务注入事故，任务不在内存中时返回 ErrMissionNotFound
+func (ms *MissionService) InjectAccident(id uint, a *db.Accident) (uint, error) {
+	v, ok := ms.m.Load(id)
+	if !ok {
+		return 0, ErrMissionNotFound
+	}
+	return v.(*SingleMissionService).InjectAccident(a)
+}
+
+// InjectStatus 向运行中的任务注入状态变化，任务不在内存中时返回 ErrMissionNotFound
+func (ms *MissionService) InjectStatus(id uint, eventType db.EventType, value string) (uint, error) {
+	v, ok := ms.m.Load(id)
+	if !ok {
+		return 0, ErrMissionNotFound
+	}
+	return v.(*SingleMissionService).InjectStatus(eventType, value)
+}
@@ -0,0 +1,126 @@
+package mission
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/eli-yip/rocket-control/db"
+)
+
+func newTestMissions(ids ...uint) (*MissionService, *fakeDB) {
+	var missions []*db.Mission
+	for _, id := range ids {
+		m := &db.Mission{Name: "test", CreatedBy: "commander", Status: db.MissionStatusInProgress}
+		m.ID = id
+		missions = append(missions, m)
+	}
+	fdb := newFakeDB(missions...)
+	return NewMissionService(fdb), fdb
+}
+
+func TestSlowLoadDoesNotBlockOtherMissions(t *testing.T) {
+	ms, fdb := newTestMissions(1, 2)
+	if _, err := ms.JoinMission(1, "alice"); err != nil {
+		t.Fatalf("failed to join mission 1: %v", err)
+	}
+
+	blocked, unblock := make(chan struct{}), make(chan struct{})
+	fdb.onGetMission = func(id uint) {
+		if id == 2 {
+			close(blocked)
+			<-unblock
+		}
+	}
+	joined := make(chan error)
+	go func() {
+		_, err := ms.JoinMission(2, "carol")
+		joined <- err
+	}()
+	<-blocked
+
+	// 任务 2 加载期间，任务 1 的加入和离开不受影响
+	done := make(chan error)
+	go func() {
+		if _, err := ms.JoinMission(1, "bob"); err != nil {
+			done <- err
+			return
+		}
+		done <- ms.LeaveMission(1, "bob")
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("join and leave mission 1 failed: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("mission 1 blocked by loading mission 2")
+	}
+
+	close(unblock)
+	if err := <-joined; err != nil {
+		t.Fatalf("failed to join mission 2: %v", err)
+	}
+	_ = ms.LeaveMission(1, "alice")
+	_ = ms.LeaveMission(2, "carol")
+}
+
+func TestConcurrentJoinsLoadOnce(t *testing.T) {
+	ms, fdb := newTestMissions(1)
+	var loads atomic.Int32
+	fdb.onGetMission = func(uint) {
+		loads.Add(1)
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	users := []string{"alice", "bob", "carol", "dave"}
+	var wg sync.WaitGroup
+	for _, user := range users {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := ms.JoinMission(1, user); err != nil {
+				t.Errorf("%s failed to join: %v", user, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := loads.Load(); n != 1 {
+		t.Errorf("mission loaded %d times, want 1", n)
+	}
+	v, _ := ms.m.Load(uint(1))
+	if n := v.(*SingleMissionService).MemberCount(); n != len(users) {
+		t.Errorf("%d members, want %d", n, len(users))
+	}
+	for _, user := range users {
+		_ = ms.LeaveMission(1, user)
+	}
+}
+
+func TestRejoinAfterLastLeave(t *testing.T) {
+	ms, _ := newTestMissions(1)
+	if _, err := ms.JoinMission(1, "alice"); err != nil {
+		t.Fatalf("failed to join: %v", err)
+	}
+	v, _ := ms.m.Load(uint(1))
+	first := v.(*SingleMissionService)
+	if err := ms.LeaveMission(1, "alice"); err != nil {
+		t.Fatalf("failed to leave: %v", err)
+	}
+	if _, ok := ms.m.Load(uint(1)); ok {
+		t.Fatal("stopped mission still in memory")
+	}
+
+	// 加入一个刚刚停止、还没有移除的任务时会重新加载
+	ms.m.Store(uint(1), first)
+	if _, err := ms.JoinMission(1, "alice"); err != nil {
+		t.Fatalf("failed to rejoin: %v", err)
+	}
+	v, _ = ms.m.Load(uint(1))
+	if v.(*SingleMissionService) == first {
+		t.Fatal("rejoined the stopped mission")
+	}
+	_ = ms.LeaveMission(1, "alice")
+}
@@ -0,0 +1,282 @@
+package mission
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/eli-yip/rocket-control/db"
+	"github.com/eli-yip/rocket-control/models"
+)
+
+// phaseTransitions 定义合法的飞行阶段转换
+var phaseTransitions = map[db.FlightPhase][]db.FlightPhase{
+	db.FlightPhasePreLaunch: {db.FlightPhaseCountdown},
+	db.FlightPhaseCountdown: {db.FlightPhaseAscent, db.FlightPhaseAborted},
+	db.FlightPhaseAscent:    {db.FlightPhaseOrbit, db.FlightPhaseDescent, db.FlightPhaseAborted},
+	db.FlightPhaseOrbit:     {db.FlightPhaseDescent, db.FlightPhaseAborted},
+	db.FlightPhaseDescent:   {db.FlightPhaseLanded, db.FlightPhaseAborted},
+}
+
+// commandPhases 定义每个飞行指令允许执行的阶段
+var commandPhases = map[db.EventType][]db.FlightPhase{
+	db.EventTypeLanuch: {db.FlightPhasePreLaunch},
+	db.EventTypeAbort:  {db.FlightPhaseCountdown, db.FlightPhaseAscent, db.FlightPhaseOrbit, db.FlightPhaseDescent},
+	db.EventTypeLand:   {db.FlightPhaseAscent, db.FlightPhaseOrbit},
+	db.EventTypeTest:   {db.FlightPhasePreLaunch, db.FlightPhaseLanded},
+}
+
+const countdownSeconds = 10
+
+var (
+	// 中止序列：关闭推力，稳定姿态，保证生命维持与通信
+	abortSequence = db.ProgramSteps{
+		{EventType: db.EventTypeThrust, Value: "0", Desc: "Cut thrust"},
+		{EventType: db.EventTypeStabilizer, Value: "100", Desc: "Full stabilization"},
+		{EventType: db.EventTypeTriggerLife, Value: "true", Desc: "Life support on"},
+		{EventType: db.EventTypeTriggerComms, Value: "true", Desc: "Comms on"},
+		{EventType: db.EventTypeOrbit, Value: "0", Desc: "Leave orbit"},
+	}
+	// 降落序列：离轨、逐步降低高度和推力
+	descentSequence = db.ProgramSteps{
+		{EventType: db.EventTypeOrbit, Value: "0", Desc: "Deorbit burn", Duration: 2000},
+		{EventType: db.EventTypeThrust, Value: "30", Desc: "Reduce thrust", Duration: 3000},
+		{EventType: db.EventTypeAlt, Value: "50", Desc: "Descend to half altitude", Duration: 3000},
+		{EventType: db.EventTypeAlt, Value: "0", Desc: "Touchdown", Duration: 1000},
+		{EventType: db.EventTypeThrust, Value: "0", Desc: "Engine cutoff"},
+	}
+)
+
+// ValidFlightPhase 返回 phase 是否是已知的飞行阶段
+func ValidFlightPhase(phase db.FlightPhase) bool {
+	switch phase {
+	case db.FlightPhasePreLaunch, db.FlightPhaseCountdown, db.FlightPhaseAscent, db.FlightPhaseOrbit,
+		db.FlightPhaseDescent, db.FlightPhaseLanded, db.FlightPhaseAborted:
+		return true
+	}
+	return false
+}
+
+// normalizePhase 兼容没有记录飞行阶段的旧数据
+func normalizePhase(status *db.RocketStatus) {
+	if status.Phase != "" {
+		return
+	}
+	if status.Launched {
+		status.Phase = db.FlightPhaseAscent
+	} else {
+		status.Phase = db.FlightPhasePreLaunch
+	}
+}
+
+func canTransition(from, to db.FlightPhase) bool {
+	for _, p := range phaseTransitions[from] {
+		if p == to {
+			return true
+		}
+	}
+	return false
+}
+
+// checkCommandPhase 检查指令在当前阶段是否可以执行，不可执行时返回原因
+func checkCommandPhase(eventType db.EventType, phase db.FlightPhase) error {
+	allowed := commandPhases[eventType]
+	for _, p := range allowed {
+		if p == phase {
+			return nil
+		}
+	}
+	names := make([]string, 0, len(allowed))
+	for _, p := range allowed {
+		names = append(names, string(p))
+	}
+	return fmt.Errorf("%s is not allowed in phase %s (allowed: %s)", eventType, phase, strings.Join(names, ", "))
+}
+
+// transitionLocked 切换飞行阶段，调用者需要持有 s.lock
+func (s *SingleMissionService) transitionLocked(to db.FlightPhase) error {
+	from := s.status.Phase
+	if !canTransition(from, to) {
+		return fmt.Errorf("invalid phase transition from %s to %s", from, to)
+	}
+	s.status.Phase = to
+	if to == db.FlightPhaseAscent {
+		s.status.Launched = true
+	}
+	if err := s.db.UpdateSystemStatus(s.info.ID, *s.status); err != nil {
+		s.logger.Error("failed to update flight phase in db", zap.Error(err))
+	}
+	s.logger.Info("flight phase changed", zap.String("from", string(from)), zap.String("to", string(to)))
+
+	phaseEvent := models.Event{
+		EventType: db.EventTypePhase,
+		Status:    db.EventStatusCompleted,
+		Value:     string(to),
+		CreatedBy: "system",
+	}
+	s.spawn(func() { s.recordEvent(phaseEvent, db.EventStatusCompleted) })
+	s.broadcast(phaseEvent)
+	return nil
+}
+
+func (s *SingleMissionService) transition(to db.FlightPhase) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.transitionLocked(to)
+}
+
+func (s *SingleMissionService) currentPhase() db.FlightPhase {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.status.Phase
+}
+
+// failEvent 将事件标记为失败，记录并广播失败原因
+func (s *SingleMissionService) failEvent(event models.Event, reason string) {
+	event.Status = db.EventStatusFailed
+	event.Reason = reason
+	_ = s.db.UpdateEventStatus(event.ID, db.EventStatusFailed)
+	_ = s.db.UpdateEventDesc(event.ID, reason)
+	s.broadcast(event)
+}
+
+// handleFlightCommand 处理 launch、abort、land、test 指令，在当前阶段不可执行的指令会直接失败。
+// 返回指令是否被接受，test 同步执行，返回自检是否通过。
+func (s *SingleMissionService) handleFlightCommand(event models.Event, logger *zap.Logger) bool {
+	if err := checkCommandPhase(event.EventType, s.currentPhase()); err != nil {
+		logger.Warn("flight command rejected", zap.Error(err))
+		s.failEvent(event, err.Error())
+		return false
+	}
+
+	switch event.EventType {
+	case db.EventTypeLanuch:
+		s.spawn(func() { s.launch(event, logger) })
+	case db.EventTypeAbort:
+		s.spawn(func() { s.abort(event, logger) })
+	case db.EventTypeLand:
+		s.spawn(func() { s.land(event, logger) })
+	case db.EventTypeTest:
+		return s.test(event, logger)
+	}
+	return true
+}
+
+// launch 进入倒计时，倒计时结束后进入上升阶段，倒计时期间可以被中止
+func (s *SingleMissionService) launch(event models.Event, logger *zap.Logger) {
+	if err := s.transition(db.FlightPhaseCountdown); err != nil {
+		s.failEvent(event, err.Error())
+		return
+	}
+	_ = s.db.UpdateEventStatus(event.ID, db.EventStatusInProgress)
+	for i := countdownSeconds; i >= 1; i-- {
+		if s.currentPhase() != db.FlightPhaseCountdown {
+			logger.Info("countdown interrupted", zap.Int("remaining", i))
+			s.finishComplexEvent(event, db.EventStatusCancelled)
+			return
+		}
+		s.broadcast(models.Event{
+			ID:        event.ID,
+			EventType: db.EventTypeLanuch,
+			Status:    db.EventStatusInProgress,
+			Value:     strconv.Itoa(i),
+			CreatedBy: event.CreatedBy,
+		})
+		select {
+		case <-s.done:
+			s.finishComplexEvent(event, db.EventStatusCancelled)
+			return
+		case <-s.clock.After(1 * time.Second):
+		}
+	}
+
+	// 发射成功，更新状态
+	if err := s.transition(db.FlightPhaseAscent); err != nil {
+		// 倒计时的最后一秒被中止
+		logger.Info("launch interrupted", zap.Error(err))
+		s.finishComplexEvent(event, db.EventStatusCancelled)
+		return
+	}
+	s.finishComplexEvent(event, db.EventStatusCompleted)
+}
+
+// abort 进入中止阶段，取消正在运行的自定义程序并执行中止序列
+func (s *SingleMissionService) abort(event models.Event, logger *zap.Logger) {
+	if err := s.transition(db.FlightPhaseAborted); err != nil {
+		s.failEvent(event, err.Error())
+		return
+	}
+	s.cancelAllPrograms()
+
+	event.Status = db.EventStatusInProgress
+	_ = s.db.UpdateEventStatus(event.ID, db.EventStatusInProgress)
+	s.broadcast(event)
+	s.finishComplexEvent(event, s.runSteps(context.Background(), event, abortSequence, logger))
+}
+
+// land 进入下降阶段，执行降落序列，完成后着陆
+func (s *SingleMissionService) land(event models.Event, logger *zap.Logger) {
+	if err := s.transition(db.FlightPhaseDescent); err != nil {
+		s.failEvent(event, err.Error())
+		return
+	}
+
+	// 降落序列和自定义程序一样会被中止取消
+	ctx, stop := s.startProgram(event, "descent")
+	defer stop()
+
+	event.Status = db.EventStatusInProgress
+	_ = s.db.UpdateEventStatus(event.ID, db.EventStatusInProgress)
+	s.broadcast(event)
+
+	status := s.runSteps(ctx, event, descentSequence, logger)
+	if status == db.EventStatusCompleted {
+		if err := s.transition(db.FlightPhaseLanded); err != nil {
+			// 降落过程中被中止
+			logger.Info("landing interrupted", zap.Error(err))
+			status = db.EventStatusCancelled
+		}
+	}
+	s.finishComplexEvent(event, status)
+}
+
+// test 执行发射前（或着陆后）的系统自检，任一子系统未就绪则失败，返回自检是否通过
+func (s *SingleMissionService) test(event models.Event, logger *zap.Logger) bool {
+	s.lock.Lock()
+	var problems []string
+	if !s.settings.Power {
+		problems = append(problems, "power off")
+	}
+	if !s.settings.Comms {
+		problems = append(problems, "comms off")
+	}
+	if !s.settings.Nav {
+		problems = append(problems, "nav off")
+	}
+	if !s.settings.Life {
+		problems = append(problems, "life support off")
+	}
+	if s.status.HullLevel < hullMin {
+		problems = append(problems, "hull integrity low")
+	}
+	if s.status.FuelLevel < fuelMin {
+		problems = append(problems, "fuel low")
+	}
+	if s.status.OxygenLevel < oxygenMin {
+		problems = append(problems, "oxygen low")
+	}
+	s.lock.Unlock()
+
+	if len(problems) > 0 {
+		logger.Info("system test failed", zap.Strings("problems", problems))
+		s.failEvent(event, "system test failed: "+strings.Join(problems, ", "))
+		return false
+	}
+	_ = s.db.UpdateEventDesc(event.ID, "All systems go.")
+	s.finishComplexEvent(event, db.EventStatusCompleted)
+	return true
+}
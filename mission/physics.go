@@ -0,0 +1,161 @@
+package mission
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/eli-yip/rocket-control/db"
+)
+
+// PhysicsModel 根据火箭设置推进火箭状态，每个任务在创建时选择一个物理模型
+type PhysicsModel interface {
+	// Name 返回模型名称，与 db.Mission.PhysicsModel 对应
+	Name() string
+	// Step 返回 status 在 setting 作用下经过 dt 之后的新状态
+	Step(setting db.RocketSetting, status db.RocketStatus, dt time.Duration) db.RocketStatus
+}
+
+const (
+	PhysicsModelBasic     = "basic"
+	PhysicsModelNewtonian = "newtonian"
+)
+
+var ErrUnknownPhysicsModel = errors.New("unknown physics model")
+
+var physicsModels = map[string]PhysicsModel{
+	PhysicsModelBasic:     basicPhysics{},
+	PhysicsModelNewtonian: newtonianPhysics{},
+}
+
+// GetPhysicsModel 按名称返回物理模型，名称为空时返回默认模型
+func GetPhysicsModel(name string) (PhysicsModel, error) {
+	if name == "" {
+		name = PhysicsModelBasic
+	}
+	m, ok := physicsModels[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownPhysicsModel, name)
+	}
+	return m, nil
+}
+
+// basicPhysics 是默认的经验模型，各项状态按固定速率变化
+type basicPhysics struct{}
+
+func (basicPhysics) Name() string { return PhysicsModelBasic }
+
+func (basicPhysics) Step(setting db.RocketSetting, status db.RocketStatus, dt time.Duration) db.RocketStatus {
+	sec := dt.Seconds()
+
+	// HullLevel 缓慢下降
+	status.HullLevel = math.Max(status.HullLevel-0.05*sec, 0)
+	// FuelLevel 消耗与 Thrust 相关
+	status.FuelLevel = math.Max(status.FuelLevel-(0.1+0.2*setting.Thrust/100)*sec, 0)
+	// OxygenLevel 消耗与 Life 支持和 Thrust 相关
+	oxygenRate := 0.05
+	if setting.Life {
+		oxygenRate += 0.05
+	}
+	oxygenRate += 0.1 * setting.Thrust / 100
+	status.OxygenLevel = math.Max(status.OxygenLevel-oxygenRate*sec, 0)
+	// 温度与 Thrust 和 PowerLevel 相关，有一定冷却
+	tempDelta := 0.05*setting.Thrust + 0.03*setting.PowerLevel - 0.1
+	status.TemperatureLevel = math.Max(status.TemperatureLevel+tempDelta*sec, 0)
+	// 压力与 Altitude 和 Fuel 相关
+	pressureDelta := 0.05*setting.Altitude - 0.03*setting.Fuel
+	status.PressureLevel = math.Max(status.PressureLevel+pressureDelta*sec, 0)
+
+	return status
+}
+
+// newtonianPhysics 基于质量、推重比对高度和速度做积分，其余状态由飞行状态推导
+type newtonianPhysics struct{}
+
+const (
+	dryMass         = 20_000.0    // 干重（kg）
+	fuelCapacity    = 80_000.0    // 满载燃料质量（kg）
+	maxThrustForce  = 1_500_000.0 // 最大推力（N）
+	specificImpulse = 300.0       // 比冲（s）
+	standardGravity = 9.80665     // 标准重力加速度（m/s²）
+	earthRadius     = 6_371.0     // 地球半径（km）
+	orbitalVelocity = 7_800.0     // 近地轨道速度（m/s）
+	scaleHeight     = 8.5         // 大气标高（km）
+	seaLevelDensity = 1.225       // 海平面空气密度（kg/m³）
+	ambientTemp     = 20.0        // 环境温度
+)
+
+func (newtonianPhysics) Name() string { return PhysicsModelNewtonian }
+
+func (newtonianPhysics) Step(setting db.RocketSetting, status db.RocketStatus, dt time.Duration) db.RocketStatus {
+	sec := dt.Seconds()
+	onGround := status.Phase == db.FlightPhasePreLaunch || status.Phase == db.FlightPhaseCountdown ||
+		status.Phase == db.FlightPhaseLanded
+
+	// 1. 推力与燃料消耗，关闭电源或燃料耗尽时发动机不工作
+	fuelMass := status.FuelLevel / 100 * fuelCapacity
+	throttle := clamp(setting.Thrust/100, 0, 1)
+	if !setting.Power || fuelMass <= 0 || status.Phase == db.FlightPhaseCountdown {
+		throttle = 0
+	}
+	// Speed 设置为速度上限（占轨道速度的百分比），超过上限时关闭推力
+	if setting.Speed > 0 && status.VelocityLevel >= setting.Speed/100*orbitalVelocity {
+		throttle = 0
+	}
+	force := throttle * maxThrustForce
+	burned := math.Min(force/(specificImpulse*standardGravity)*sec, fuelMass)
+	fuelMass -= burned
+	status.FuelLevel = fuelMass / fuelCapacity * 100
+	mass := dryMass + fuelMass
+
+	// 2. 高度与速度积分
+	altitude := status.AltitudeLevel
+	gravity := standardGravity * math.Pow(earthRadius/(earthRadius+altitude), 2)
+	acceleration := force/mass - gravity
+	switch {
+	case status.Phase == db.FlightPhaseOrbit && setting.Orbit > 0:
+		// 入轨后轨道保持抵消重力，垂直速度逐渐归零
+		acceleration = -status.VelocityLevel * math.Min(setting.Orbit/100, 1)
+	case onGround && acceleration < 0:
+		// 推重比小于 1 时火箭停在发射台上
+		acceleration = 0
+	}
+	velocity := status.VelocityLevel + acceleration*sec
+	altitude += (status.VelocityLevel + velocity) / 2 * sec / 1000
+	if altitude <= 0 {
+		altitude, velocity = 0, math.Max(velocity, 0)
+	}
+	status.AltitudeLevel, status.VelocityLevel = altitude, velocity
+
+	// 3. 动压造成船体损耗，Stabilizer 降低结构应力
+	density := seaLevelDensity * math.Exp(-altitude/scaleHeight)
+	dynamicPressure := 0.5 * density * velocity * velocity / 1000 // kPa
+	stress := dynamicPressure / 50 * (1 - clamp(setting.Stabilizer/100, 0, 0.9))
+	if status.TemperatureLevel > tempMax {
+		stress += (status.TemperatureLevel - tempMax) / 100
+	}
+	status.HullLevel = math.Max(status.HullLevel-stress*sec, 0)
+
+	// 4. 温度：发动机与气动加热，Temperature 设置越高散热越强
+	heating := 0.5*throttle + dynamicPressure/50 + 0.002*setting.PowerLevel
+	cooling := 0.01 * (1 + setting.Temperature/100) * (status.TemperatureLevel - ambientTemp)
+	status.TemperatureLevel = math.Max(status.TemperatureLevel+(heating-cooling)*sec, 0)
+
+	// 5. 氧气：生命维持消耗，船体受损后泄漏
+	oxygenRate := 0.02
+	if setting.Life {
+		oxygenRate += 0.03 * (1 + setting.Oxygen/100)
+	}
+	oxygenRate += (100 - status.HullLevel) / 1000
+	status.OxygenLevel = math.Max(status.OxygenLevel-oxygenRate*sec, 0)
+
+	// 6. 舱压向 Pressure 设置靠拢，随外部气压降低和船体受损泄漏
+	target := setting.Pressure * math.Min(status.HullLevel/hullMin, 1)
+	status.PressureLevel += (target - status.PressureLevel) * math.Min(0.1*sec, 1)
+	status.PressureLevel = math.Max(status.PressureLevel-(1-density/seaLevelDensity)*0.05*sec, 0)
+
+	return status
+}
+
+func clamp(v, lo, hi float64) float64 { return math.Max(lo, math.Min(hi, v)) }
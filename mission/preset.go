@@ -0,0 +1,54 @@
+package mission
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/eli-yip/rocket-control/db"
+	"github.com/eli-yip/rocket-control/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// handlePresetEvent 将预设一次性应用到 RocketSetting：只写一次数据库、只广播一次，预设名称记录在事件的 Desc 中
+func (s *SingleMissionService) handlePresetEvent(event models.Event, logger *zap.Logger) {
+	id, err := strconv.ParseUint(event.Value, 10, 64)
+	if err != nil {
+		s.failEvent(event, fmt.Sprintf("invalid preset id %q", event.Value))
+		return
+	}
+	preset, err := s.db.GetPreset(uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			s.failEvent(event, fmt.Sprintf("preset %d not found", id))
+			return
+		}
+		logger.Error("failed to get preset", zap.Uint64("preset_id", id), zap.Error(err))
+		s.failEvent(event, fmt.Sprintf("failed to load preset %d", id))
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if err := s.db.UpdateSystemSetting(s.info.ID, preset.RocketSetting); err != nil {
+		logger.Error("failed to update rocket settings in db", zap.Error(err))
+		s.failEvent(event, "failed to apply preset")
+		return
+	}
+	*s.settings = preset.RocketSetting
+	logger.Info("preset applied", zap.Uint("preset_id", preset.ID), zap.String("preset", preset.Name))
+
+	_ = s.db.UpdateEventDesc(event.ID, preset.Name)
+	_ = s.db.UpdateEventStatus(event.ID, db.EventStatusCompleted)
+	event.Status = db.EventStatusCompleted
+	setting := *s.settings
+	event.Setting = &setting
+	s.broadcast(event)
+
+	// 和单独设置轨道控制一样，上升阶段打开轨道控制即视为入轨
+	if s.settings.Orbit > 0 && s.status.Phase == db.FlightPhaseAscent {
+		_ = s.transitionLocked(db.FlightPhaseOrbit)
+	}
+}
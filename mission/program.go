@@ -0,0 +1,148 @@
+package mission
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/eli-yip/rocket-control/db"
+	"github.com/eli-yip/rocket-control/models"
+)
+
+var ErrInvalidProgramStep = errors.New("invalid program step")
+
+// 自定义程序中可以执行的事件类型，Value 分别需要解析为 float、bool，命令类事件忽略 Value。
+// launch、abort、land 会异步切换飞行阶段，abort 还会取消正在运行的程序，因此不能作为程序步骤。
+var (
+	settingStepTypes = map[db.EventType]bool{
+		db.EventTypeThrust: true, db.EventTypeAlt: true, db.EventTypeFuel: true, db.EventTypeSpeed: true,
+		db.EventTypeTemp: true, db.EventTypeStabilizer: true, db.EventTypeOxygen: true, db.EventTypeOrbit: true,
+		db.EventTypePowerLevel: true, db.EventTypePressure: true,
+		db.EventTypeHullChange: true, db.EventTypeFuelChange: true, db.EventTypeOxygenChange: true,
+		db.EventTypeTempChange: true, db.EventTypePressureChange: true,
+		db.EventTypeAltitudeChange: true, db.EventTypeVelocityChange: true,
+	}
+	triggerStepTypes = map[db.EventType]bool{
+		db.EventTypeTriggerPower: true, db.EventTypeTriggerComms: true,
+		db.EventTypeTriggerNav: true, db.EventTypeTriggerLife: true,
+	}
+	commandStepTypes = map[db.EventType]bool{
+		db.EventTypeTest: true, db.EventTypeDiagnoseStart: true,
+	}
+	phaseCommandTypes = map[db.EventType]bool{
+		db.EventTypeLanuch: true, db.EventTypeAbort: true, db.EventTypeLand: true,
+	}
+)
+
+// ValidateProgramSteps 检查自定义程序的每一步是否可以执行
+func ValidateProgramSteps(steps db.ProgramSteps) error {
+	if len(steps) == 0 {
+		return fmt.Errorf("%w: program has no steps", ErrInvalidProgramStep)
+	}
+	return validateSteps(steps, 0)
+}
+
+// validateSteps 检查 depth 层的步骤，控制流步骤的嵌套步骤位于 depth+1 层
+func validateSteps(steps db.ProgramSteps, depth int) error {
+	for i, step := range steps {
+		if err := validateProgramStep(step, depth); err != nil {
+			return fmt.Errorf("step %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+func validateProgramStep(step db.ProgramStep, depth int) error {
+	if step.Duration < 0 {
+		return fmt.Errorf("%w: negative duration %d", ErrInvalidProgramStep, step.Duration)
+	}
+	switch {
+	case settingStepTypes[step.EventType]:
+		if _, err := strconv.ParseFloat(step.Value, 64); err != nil {
+			return fmt.Errorf("%w: %s value %q is not a number", ErrInvalidProgramStep, step.EventType, step.Value)
+		}
+	case triggerStepTypes[step.EventType]:
+		if _, err := strconv.ParseBool(step.Value); err != nil {
+			return fmt.Errorf("%w: %s value %q is not a bool", ErrInvalidProgramStep, step.EventType, step.Value)
+		}
+	case commandStepTypes[step.EventType]:
+	case phaseCommandTypes[step.EventType]:
+		return fmt.Errorf("%w: %s cannot be used as a program step", ErrInvalidProgramStep, step.EventType)
+	case isControlStep(step.EventType):
+		return validateControlStep(step, depth)
+	default:
+		return fmt.Errorf("%w: event type %q is not executable", ErrInvalidProgramStep, step.EventType)
+	}
+	return nil
+}
+
+// runningProgram 记录一个正在运行、可以被取消的复合事件
+type runningProgram struct {
+	cancel context.CancelFunc
+	mu     sync.Mutex
+	info   models.RunningProgram
+}
+
+// startProgram 登记一个可以被取消的复合事件，name 为程序名称，返回的 stop 需要在事件结束时调用
+func (s *SingleMissionService) startProgram(event models.Event, name string) (ctx context.Context, stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.programs.Store(event.ID, &runningProgram{
+		cancel: cancel,
+		info: models.RunningProgram{
+			EventID:   event.ID,
+			EventType: event.EventType,
+			Name:      name,
+			Value:     event.Value,
+			CreatedBy: event.CreatedBy,
+		},
+	})
+	return ctx, func() {
+		s.programs.Delete(event.ID)
+		cancel()
+	}
+}
+
+// setProgramProgress 更新复合事件的执行进度，未登记的事件（例如事故）会被忽略
+func (s *SingleMissionService) setProgramProgress(eventID uint, step, steps int) {
+	v, ok := s.programs.Load(eventID)
+	if !ok {
+		return
+	}
+	p := v.(*runningProgram)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.info.Step, p.info.Steps = step, steps
+}
+
+// cancelProgram 取消一个正在运行的复合事件，返回是否找到该事件
+func (s *SingleMissionService) cancelProgram(eventID uint) bool {
+	v, ok := s.programs.Load(eventID)
+	if !ok {
+		return false
+	}
+	v.(*runningProgram).cancel()
+	return true
+}
+
+// cancelAllPrograms 取消所有正在运行的复合事件
+func (s *SingleMissionService) cancelAllPrograms() {
+	s.programs.Range(func(_, v any) bool {
+		v.(*runningProgram).cancel()
+		return true
+	})
+}
+
+// runningPrograms 返回正在运行的复合事件
+func (s *SingleMissionService) runningPrograms() []models.RunningProgram {
+	programs := []models.RunningProgram{}
+	s.programs.Range(func(_, v any) bool {
+		p := v.(*runningProgram)
+		p.mu.Lock()
+		programs = append(programs, p.info)
+		p.mu.Unlock()
+		return true
+	})
+	return programs
+}
@@ -0,0 +1,155 @@
+package mission
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/eli-yip/rocket-control/db"
+	"github.com/eli-yip/rocket-control/models"
+)
+
+// 重排窗口使用任务的模拟时间，随模拟速度缩放，暂停期间不放行操作
+const (
+	reorderWindow  = 100 * time.Millisecond // 操作到达后至少等待的时间
+	reorderMaxHold = 5 * reorderWindow      // 等待缺失序号的最长时间
+)
+
+type pendingAction struct {
+	event    models.Event
+	arrival  time.Time // 到达时的模拟时间，用于计算等待时间
+	received time.Time // 到达时的真实时间，客户端未提供时间戳时作为发送时间
+	order    uint64    // 到达顺序，发送时间和序号都相同时使用
+}
+
+// sendTime 返回排序使用的发送时间，客户端未提供时间戳时使用到达的真实时间
+func (p pendingAction) sendTime() time.Time {
+	if !p.event.ClientTime.IsZero() {
+		return p.event.ClientTime
+	}
+	return p.received
+}
+
+// before 返回 p 是否应该在 q 之前执行：先比较发送时间，再比较客户端序号，最后比较到达顺序
+func (p pendingAction) before(q pendingAction) bool {
+	if pt, qt := p.sendTime(), q.sendTime(); !pt.Equal(qt) {
+		return pt.Before(qt)
+	}
+	if p.event.Seq != q.event.Seq {
+		return p.event.Seq < q.event.Seq
+	}
+	return p.order < q.order
+}
+
+// reorderBuffer 在一个较短的窗口内缓存客户端发来的操作。
+// 同一客户端的操作按 (发送时间, 序号) 执行，序号不连续时等待缺失的操作；
+// 不同客户端之间按同样的顺序合并，但只保证同一次放行的操作之间有序，
+// 已经放行的操作不会因为之后到达、发送时间更早的操作而重排。
+type reorderBuffer struct {
+	mu       sync.Mutex
+	pending  map[string][]pendingAction // key: user
+	lastSeq  map[string]uint64          // 每个客户端最后一个已放行的序号
+	arrivals uint64
+}
+
+func newReorderBuffer() *reorderBuffer {
+	return &reorderBuffer{
+		pending: make(map[string][]pendingAction),
+		lastSeq: make(map[string]uint64),
+	}
+}
+
+// push 缓存一个操作，now 为模拟时间，received 为真实时间
+func (b *reorderBuffer) push(event models.Event, now, received time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.arrivals++
+	user := event.CreatedBy
+	b.pending[user] = append(b.pending[user], pendingAction{event: event, arrival: now, received: received, order: b.arrivals})
+}
+
+// reset 清除客户端最后放行的序号，客户端重新连接后序号从 1 开始
+func (b *reorderBuffer) reset(user string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.lastSeq, user)
+}
+
+// release 返回已经可以执行的操作，顺序即执行顺序
+func (b *reorderBuffer) release(now time.Time) []models.Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// 1. 每个客户端按发送时间和序号排序，取出可以放行的前缀
+	queues := make(map[string][]pendingAction)
+	for user, actions := range b.pending {
+		sort.Slice(actions, func(i, j int) bool { return actions[i].before(actions[j]) })
+		n := 0
+		for _, a := range actions {
+			if now.Sub(a.arrival) < reorderWindow {
+				break
+			}
+			// 序号不连续时等待缺失的操作，超过最长等待时间后放弃等待
+			if seq := a.event.Seq; seq > 0 && seq > b.lastSeq[user]+1 && now.Sub(a.arrival) < reorderMaxHold {
+				break
+			}
+			if a.event.Seq > b.lastSeq[user] {
+				b.lastSeq[user] = a.event.Seq
+			}
+			n++
+		}
+		if n == 0 {
+			continue
+		}
+		queues[user] = actions[:n]
+		if n == len(actions) {
+			delete(b.pending, user)
+		} else {
+			b.pending[user] = actions[n:]
+		}
+	}
+
+	// 2. 合并各客户端的队列，每次取最早的队首，保持客户端内的顺序
+	var released []models.Event
+	for len(queues) > 0 {
+		var next string
+		for user, q := range queues {
+			if next == "" || q[0].before(queues[next][0]) {
+				next = user
+			}
+		}
+		released = append(released, queues[next][0].event)
+		if queues[next] = queues[next][1:]; len(queues[next]) == 0 {
+			delete(queues, next)
+		}
+	}
+	return released
+}
+
+// SubmitAction 接收客户端发来的操作，经过重排窗口后再进入事件队列。
+// 暂停期间重排窗口不会放行操作，pause、resume 直接进入事件队列。
+func (s *SingleMissionService) SubmitAction(event models.Event) {
+	if event.EventType == db.EventTypePause || event.EventType == db.EventTypeResume {
+		s.AddEvent(event)
+		return
+	}
+	s.actions.push(event, s.clock.Now(), time.Now())
+}
+
+// reorder 定期放行重排窗口中的操作，依次加入事件队列
+func (s *SingleMissionService) reorder() {
+	ticker := s.clock.NewTicker(reorderWindow / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C():
+			for _, event := range s.actions.release(now) {
+				s.AddEvent(event)
+			}
+		case <-s.done:
+			s.logger.Info("action reorder stopped")
+			return
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package mission
+
+import (
+	"testing"
+	"time"
+
+	"github.com/eli-yip/rocket-control/db"
+	"github.com/eli-yip/rocket-control/models"
+)
+
+func TestReorderBufferPerClientOrder(t *testing.T) {
+	b := newReorderBuffer()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	sent := now.Add(-time.Second)
+	action := func(user string, seq uint64, offset time.Duration) models.Event {
+		return models.Event{EventType: db.EventTypeThrust, CreatedBy: user, Seq: seq, ClientTime: sent.Add(offset)}
+	}
+
+	// seq 2 先到达，等待 seq 1
+	b.push(action("alice", 2, 20*time.Millisecond), now, now)
+	if got := b.release(now.Add(reorderWindow)); len(got) != 0 {
+		t.Fatalf("released %d actions before the missing seq arrived", len(got))
+	}
+	b.push(action("alice", 1, 10*time.Millisecond), now, now)
+	b.push(action("bob", 1, 15*time.Millisecond), now, now)
+	got := b.release(now.Add(reorderWindow))
+	want := []struct {
+		user string
+		seq  uint64
+	}{{"alice", 1}, {"bob", 1}, {"alice", 2}}
+	if len(got) != len(want) {
+		t.Fatalf("released %d actions, want %d", len(got), len(want))
+	}
+	for i, w := range want {
+		if got[i].CreatedBy != w.user || got[i].Seq != w.seq {
+			t.Errorf("action %d is %s/%d, want %s/%d", i, got[i].CreatedBy, got[i].Seq, w.user, w.seq)
+		}
+	}
+
+	// 重新连接后序号从 1 开始
+	b.reset("alice")
+	b.push(action("alice", 1, 30*time.Millisecond), now, now)
+	if got := b.release(now.Add(reorderWindow)); len(got) != 1 {
+		t.Fatalf("released %d actions after reconnect, want 1", len(got))
+	}
+}
+
+func TestReorderUsesMissionClock(t *testing.T) {
+	s, fdb, clock := newTestMission(t)
+	go s.reorder()
+	waitFor(t, "reorder ticker", func() bool { return clock.pendingTimers() == 1 })
+
+	e := addTestEvent(t, fdb, db.EventTypeThrust, "50", "commander")
+	clock.Pause()
+	s.SubmitAction(e)
+	clock.Advance(time.Minute)
+	time.Sleep(10 * time.Millisecond)
+	if len(s.events) != 0 {
+		t.Fatal("action released while paused")
+	}
+
+	clock.Resume()
+	clock.Advance(reorderWindow)
+	waitFor(t, "action release", func() bool { return len(s.events) == 1 })
+}
@@ -0,0 +1,112 @@
+package mission
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/eli-yip/rocket-control/db"
+	"github.com/eli-yip/rocket-control/models"
+	"go.uber.org/zap"
+)
+
+// ValidateScenarioEvents 检查剧本中的事件，事故是否存在需要调用方另外检查
+func ValidateScenarioEvents(events []db.ScenarioEvent) error {
+	for i, ev := range events {
+		if err := validateScenarioEvent(ev); err != nil {
+			return fmt.Errorf("event %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+func validateScenarioEvent(ev db.ScenarioEvent) error {
+	if ev.At < 0 {
+		return fmt.Errorf("%w: negative time %d", ErrInvalidProgramStep, ev.At)
+	}
+	if (ev.Accident == "") == (ev.EventType == "") {
+		return fmt.Errorf("%w: exactly one of accident and event_type is required", ErrInvalidProgramStep)
+	}
+	if ev.Accident != "" {
+		return nil
+	}
+	if isControlStep(ev.EventType) {
+		return fmt.Errorf("%w: control flow is not allowed in scenario", ErrInvalidProgramStep)
+	}
+	return validateProgramStep(db.ProgramStep{EventType: ev.EventType, Value: ev.Value}, 0)
+}
+
+// inFlight 返回火箭是否处于飞行中，只有飞行中任务经过时间才会增加
+func inFlight(phase db.FlightPhase) bool {
+	return phase == db.FlightPhaseAscent || phase == db.FlightPhaseOrbit || phase == db.FlightPhaseDescent
+}
+
+// loadScenario 加载任务的训练剧本，已经过去的事件不会再次注入
+func (s *SingleMissionService) loadScenario() {
+	if s.info.ScenarioID == 0 {
+		return
+	}
+	sc, err := s.db.GetScenario(s.info.ScenarioID)
+	if err != nil {
+		// 剧本被删除不影响任务运行
+		s.logger.Error("failed to get scenario", zap.Uint("scenario_id", s.info.ScenarioID), zap.Error(err))
+		return
+	}
+	sort.SliceStable(sc.Events, func(i, j int) bool { return sc.Events[i].At < sc.Events[j].At })
+	s.scenario = sc
+	if s.status.ElapsedTime > 0 {
+		for s.scenarioNext < len(sc.Events) && float64(sc.Events[s.scenarioNext].At) < s.status.ElapsedTime {
+			s.scenarioNext++
+		}
+	}
+}
+
+// randomAccidentsDisabled 返回剧本是否关闭了随机事故
+func (s *SingleMissionService) randomAccidentsDisabled() bool {
+	return s.scenario != nil && s.scenario.DisableRandomAccidents
+}
+
+// dueScenarioEventsLocked 返回到达注入时间的剧本事件，调用者需要持有 s.lock
+func (s *SingleMissionService) dueScenarioEventsLocked() []db.ScenarioEvent {
+	if s.scenario == nil || !inFlight(s.status.Phase) {
+		return nil
+	}
+	start := s.scenarioNext
+	for s.scenarioNext < len(s.scenario.Events) && float64(s.scenario.Events[s.scenarioNext].At) <= s.status.ElapsedTime {
+		s.scenarioNext++
+	}
+	return s.scenario.Events[start:s.scenarioNext]
+}
+
+// injectScenarioEvents 按顺序注入剧本事件
+func (s *SingleMissionService) injectScenarioEvents(evs []db.ScenarioEvent) {
+	for _, ev := range evs {
+		s.injectScenarioEvent(ev)
+	}
+}
+
+// injectScenarioEvent 注入剧本事件，事故和随机事故一样执行，外部事件由 system 发起
+func (s *SingleMissionService) injectScenarioEvent(ev db.ScenarioEvent) {
+	logger := s.logger.With(zap.Int("at", ev.At))
+	if ev.Accident == "" {
+		logger.Info("injecting scenario event", zap.String("event_type", string(ev.EventType)), zap.String("value", ev.Value))
+		s.AddEvent(models.Event{
+			EventType: ev.EventType,
+			Value:     ev.Value,
+			CreatedBy: "system",
+		})
+		return
+	}
+
+	a, err := s.db.GetAccidentByName(ev.Accident)
+	if err != nil {
+		logger.Error("failed to get scenario accident", zap.String("accident", ev.Accident), zap.Error(err))
+		return
+	}
+	steps, err := a.ProgramSteps()
+	if err != nil {
+		logger.Error("failed to parse scenario accident steps", zap.String("accident", ev.Accident), zap.Error(err))
+		return
+	}
+	logger.Info("injecting scenario accident", zap.String("accident", a.Name))
+	s.scheduleAccident(a, steps, false)
+}
@@ -0,0 +1,27 @@
+package mission
+
+import (
+	"testing"
+
+	"github.com/eli-yip/rocket-control/db"
+)
+
+func TestScenarioEventsInjectedInOrder(t *testing.T) {
+	s, _, clock := newTestMission(t)
+	values := []string{"10", "20", "30", "40"}
+	s.scenario = &db.Scenario{}
+	for _, v := range values {
+		s.scenario.Events = append(s.scenario.Events, db.ScenarioEvent{At: 1, EventType: db.EventTypeThrust, Value: v})
+	}
+	s.status.Phase = db.FlightPhaseAscent
+
+	go s.adjustStatus()
+	waitFor(t, "status ticker", func() bool { return clock.pendingTimers() == 1 })
+	clock.Advance(statusTickInterval)
+
+	for i, want := range values {
+		if got := <-s.events; got.Value != want {
+			t.Fatalf("scenario event %d has value %s, want %s", i, got.Value, want)
+		}
+	}
+}
@@ -0,0 +1,318 @@
+package mission
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/eli-yip/rocket-control/db"
+	"github.com/eli-yip/rocket-control/log"
+	"github.com/eli-yip/rocket-control/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const scheduleInterval = time.Second
+
+// missionWarnings 是任务结束前发送提醒的剩余模拟时间，从早到晚排列
+var missionWarnings = []time.Duration{5 * time.Minute, time.Minute}
+
+// Scheduler 按任务的 StartTime 开始任务（真实时间），任务开始后经过 Duration 模拟时间时结束任务
+type Scheduler struct {
+	db     db.Iface
+	ms     *MissionService
+	logger *zap.Logger
+}
+
+func NewScheduler(db db.Iface, ms *MissionService) *Scheduler {
+	return &Scheduler{
+		db:     db,
+		ms:     ms,
+		logger: log.DefaultLogger.With(zap.String("component", "scheduler")),
+	}
+}
+
+// Run 定期检查需要开始和结束的任务，ctx 结束时返回
+func (sc *Scheduler) Run(ctx context.Context) {
+	sc.logger.Info("scheduler started")
+	ticker := time.NewTicker(scheduleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			sc.logger.Info("scheduler stopped")
+			return
+		case now := <-ticker.C:
+			sc.tick(now)
+		}
+	}
+}
+
+func (sc *Scheduler) tick(now time.Time) {
+	list, err := sc.db.GetScheduledMissions()
+	if err != nil {
+		sc.logger.Error("failed to get scheduled missions", zap.Error(err))
+		return
+	}
+	for _, m := range list {
+		if err := sc.schedule(m, now); err != nil {
+			sc.logger.Error("failed to schedule mission", zap.Uint("mission", m.ID), zap.Error(err))
+		}
+	}
+}
+
+// schedule 开始到达开始时间的任务，结束经过 Duration 模拟时间的任务，并在结束前提醒成员
+func (sc *Scheduler) schedule(m *db.Mission, now time.Time) error {
+	// 服务重启后进行中的任务不在内存中，需要重新启动
+	if m.Status == db.MissionStatusPending {
+		if now.Before(m.StartTime) {
+			return nil
+		}
+		if err := sc.ms.StartMission(m.ID, now); err != nil {
+			return fmt.Errorf("failed to start mission: %w", err)
+		}
+		sc.logger.Info("mission started", zap.Uint("mission", m.ID), zap.Int("duration", m.Duration))
+	} else if !sc.ms.Pinned(m.ID) {
+		if err := sc.ms.StartMission(m.ID, m.StartTime); err != nil {
+			return fmt.Errorf("failed to start mission service: %w", err)
+		}
+	}
+
+	if m.Duration <= 0 {
+		return nil
+	}
+	elapsed, ok := sc.ms.MissionTime(m.ID)
+	if !ok {
+		return nil
+	}
+	remaining := time.Duration(m.Duration)*time.Minute - elapsed
+	if remaining <= 0 {
+		result, err := sc.ms.EndMission(m.ID, now)
+		if err != nil {
+			return fmt.Errorf("failed to end mission: %w", err)
+		}
+		sc.logger.Info("mission ended", zap.Uint("mission", m.ID), zap.Int("status", int(result.Status)), zap.Float64("score", result.Score))
+		return nil
+	}
+	sc.ms.WarnMission(m.ID, remaining)
+	return nil
+}
+
+// StartMission 启动任务并固定在内存中，没有成员时也继续运行。
+// 任务还没有开始时同时将数据库和内存中的任务标记为进行中，并记录开始时间 at
+func (ms *MissionService) StartMission(id uint, at time.Time) error {
+	for {
+		sms, err := ms.load(id)
+		if err != nil {
+			return err
+		}
+		err = sms.pin(at)
+		if errors.Is(err, errMissionStopped) {
+			ms.remove(id, sms)
+			continue
+		}
+		return err
+	}
+}
+
+// EndMission 结束任务并评估结果，任务在内存中时通知成员并将任务从内存中移除
+func (ms *MissionService) EndMission(id uint, at time.Time) (*db.MissionResult, error) {
+	return ms.finish(id, at, true)
+}
+
+// CancelMission 取消任务，不评估结果
+func (ms *MissionService) CancelMission(id uint, at time.Time) error {
+	_, err := ms.finish(id, at, false)
+	return err
+}
+
+func (ms *MissionService) finish(id uint, at time.Time, evaluate bool) (*db.MissionResult, error) {
+	sms, release := ms.lookup(id)
+	if release == nil {
+		result, err := sms.end(at, evaluate)
+		if err != nil {
+			return nil, err
+		}
+		ms.remove(id, sms)
+		return result, nil
+	}
+	// 任务不在内存中，直接在数据库中结束；结束前占用加载位置，期间加入的成员会等待
+	defer release(nil)
+
+	m, err := ms.db.GetMission(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrMissionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mission: %w", err)
+	}
+	if m.Status.Ended() {
+		return nil, ErrMissionEnded
+	}
+	if !evaluate {
+		return nil, ms.db.EndMission(id, db.MissionStatusCancelled, nil, at)
+	}
+
+	setting, status := m.InitialState()
+	state, err := ms.db.GetSystemState(id)
+	if err == nil {
+		setting, status = state.RocketSetting, state.RocketStatus
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to get system state: %w", err)
+	}
+	result, err := evaluateMission(ms.db, m, setting, status, at)
+	if err != nil {
+		return nil, err
+	}
+	if err = ms.db.EndMission(id, result.Status, result, at); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Pinned 返回任务是否已经由调度器启动并固定在内存中
+func (ms *MissionService) Pinned(id uint) bool {
+	v, ok := ms.m.Load(id)
+	return ok && v.(*SingleMissionService).isPinned()
+}
+
+// MissionTime 返回内存中的任务开始后经过的模拟时间，任务不在内存中时返回 false
+func (ms *MissionService) MissionTime(id uint) (time.Duration, bool) {
+	v, ok := ms.m.Load(id)
+	if !ok {
+		return 0, false
+	}
+	return v.(*SingleMissionService).missionTime(), true
+}
+
+// WarnMission 在剩余模拟时间到达 missionWarnings 时提醒运行中任务的成员任务即将结束，每个提醒只发送一次
+func (ms *MissionService) WarnMission(id uint, remaining time.Duration) {
+	v, ok := ms.m.Load(id)
+	if !ok {
+		return
+	}
+	v.(*SingleMissionService).warn(remaining)
+}
+
+// pin 固定任务并启动后台协程，任务还未开始时标记为进行中并记录开始时间，任务已经停止时返回 errMissionStopped
+func (s *SingleMissionService) pin(at time.Time) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.membersLock.Lock()
+	defer s.membersLock.Unlock()
+	if s.stoppedLocked() {
+		return errMissionStopped
+	}
+
+	if s.info.Status == db.MissionStatusPending {
+		if err := s.db.StartMission(s.info.ID, at); err != nil {
+			return fmt.Errorf("failed to start mission: %w", err)
+		}
+		s.info.Status = db.MissionStatusInProgress
+		s.info.StartTime = at
+	}
+	if !s.pinned {
+		s.logger.Info("mission pinned")
+		s.pinned = true
+	}
+	s.start()
+	return nil
+}
+
+// unscheduled 返回任务是否还未开始并且没有设置开始时间
+func (s *SingleMissionService) unscheduled() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.info.Status == db.MissionStatusPending && s.info.StartTime.IsZero()
+}
+
+func (s *SingleMissionService) isPinned() bool {
+	s.membersLock.RLock()
+	defer s.membersLock.RUnlock()
+	return s.pinned
+}
+
+// missionTime 返回任务开始后经过的模拟时间，暂停期间不增加
+func (s *SingleMissionService) missionTime() time.Duration {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return time.Duration(s.status.MissionTime * float64(time.Second))
+}
+
+// warn 发送还没有发送过、已经到达的结束提醒，只由调度器调用
+func (s *SingleMissionService) warn(remaining time.Duration) {
+	s.lock.Lock()
+	sent := s.warned
+	for sent < len(missionWarnings) && remaining <= missionWarnings[sent] {
+		sent++
+	}
+	if sent == s.warned {
+		s.lock.Unlock()
+		return
+	}
+	s.warned = sent
+	s.lock.Unlock()
+	s.notify(db.EventTypeMissionWarning, strconv.Itoa(int(remaining.Round(time.Second).Seconds())))
+}
+
+// end 结束任务：评估并记录结果，广播结束通知，断开所有成员并停止后台协程。
+// evaluate 为假时任务被取消，不评估结果。
+func (s *SingleMissionService) end(at time.Time, evaluate bool) (*db.MissionResult, error) {
+	// 调度器和 REST API 可能同时结束任务，只有第一次结束会评估和记录结果
+	s.endLock.Lock()
+	defer s.endLock.Unlock()
+
+	s.lock.Lock()
+	setting, rocketStatus := *s.settings, *s.status
+	ended := s.info.Status.Ended()
+	s.lock.Unlock()
+	if ended {
+		return nil, ErrMissionEnded
+	}
+
+	status := db.MissionStatusCancelled
+	var result *db.MissionResult
+	if evaluate {
+		var err error
+		if result, err = evaluateMission(s.db, s.info, setting, rocketStatus, at); err != nil {
+			return nil, err
+		}
+		status = result.Status
+	}
+	if err := s.db.EndMission(s.info.ID, status, result, at); err != nil {
+		return nil, err
+	}
+
+	s.lock.Lock()
+	s.info.Status, s.info.EndTime, s.info.Result = status, at, result
+	s.lock.Unlock()
+
+	s.notify(db.EventTypeMissionEnd, strconv.Itoa(int(status)))
+
+	s.membersLock.Lock()
+	defer s.membersLock.Unlock()
+	// 关闭 channel 后 Client 会在收到结束通知后断开
+	for user, ch := range s.members {
+		close(ch)
+		delete(s.members, user)
+	}
+	s.pinned = false
+	s.stop()
+	s.logger.Info("mission ended", zap.Int("status", int(status)))
+	return result, nil
+}
+
+// notify 记录并广播一个由系统产生的任务通知事件
+func (s *SingleMissionService) notify(eventType db.EventType, value string) {
+	event := models.Event{
+		EventType: eventType,
+		Status:    db.EventStatusCompleted,
+		Value:     value,
+		CreatedBy: "system",
+	}
+	s.recordEvent(event, db.EventStatusCompleted)
+	s.broadcast(event)
+}
@@ -0,0 +1,134 @@
+package mission
+
+import (
+	"testing"
+	"time"
+
+	"github.com/eli-yip/rocket-control/db"
+	"github.com/eli-yip/rocket-control/models"
+)
+
+func TestMissionTimeCountsOnlyInProgress(t *testing.T) {
+	s, _, _ := newTestMission(t)
+
+	step := func(status db.MissionStatus) {
+		s.lock.Lock()
+		s.info.Status = status
+		s.stepStatusLocked(time.Minute)
+		s.lock.Unlock()
+	}
+	step(db.MissionStatusPending)
+	step(db.MissionStatusInProgress)
+	step(db.MissionStatusInProgress)
+	if got := s.missionTime(); got != 2*time.Minute {
+		t.Fatalf("mission time %v, want 2m", got)
+	}
+}
+
+func TestMissionWarnings(t *testing.T) {
+	s, fdb, _ := newTestMission(t)
+	ch := make(chan models.WsMessage, eventBufferSize)
+	s.members["trainee"] = ch
+
+	for _, remaining := range []time.Duration{10 * time.Minute, 4 * time.Minute, 3 * time.Minute, 50 * time.Second, 10 * time.Second} {
+		s.warn(remaining)
+	}
+	close(ch)
+	var values []string
+	for m := range ch {
+		if m.Action.Type == db.EventTypeMissionWarning {
+			values = append(values, m.Action.Value)
+		}
+	}
+	if len(values) != 2 || values[0] != "240" || values[1] != "50" {
+		t.Fatalf("warnings %v, want [240 50]", values)
+	}
+	if len(fdb.events) != 2 {
+		t.Errorf("recorded %d warning events, want 2", len(fdb.events))
+	}
+}
+
+func TestStartMissionUpdatesDBAndMemory(t *testing.T) {
+	ms, fdb := newTestMissions(1)
+	fdb.missions[1].Status = db.MissionStatusPending
+	at := time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)
+
+	if err := ms.StartMission(1, at); err != nil {
+		t.Fatalf("failed to start mission: %v", err)
+	}
+	v, ok := ms.m.Load(uint(1))
+	if !ok {
+		t.Fatal("started mission not in memory")
+	}
+	s := v.(*SingleMissionService)
+	t.Cleanup(s.stop)
+
+	got, _ := fdb.GetMission(1)
+	if got.Status != db.MissionStatusInProgress || !got.StartTime.Equal(at) {
+		t.Errorf("mission in db: status %v, start time %v", got.Status, got.StartTime)
+	}
+	s.lock.Lock()
+	status, start := s.info.Status, s.info.StartTime
+	s.lock.Unlock()
+	if status != db.MissionStatusInProgress || !start.Equal(at) {
+		t.Errorf("mission in memory: status %v, start time %v", status, start)
+	}
+	if !ms.Pinned(1) || !s.running() {
+		t.Error("started mission is not pinned and running")
+	}
+
+	// 已经开始的任务再次启动时不改变开始时间
+	if err := ms.StartMission(1, at.Add(time.Hour)); err != nil {
+		t.Fatalf("failed to start mission again: %v", err)
+	}
+	if got, _ := fdb.GetMission(1); !got.StartTime.Equal(at) {
+		t.Errorf("start time changed to %v", got.StartTime)
+	}
+}
+
+func TestFirstJoinStartsUnscheduledMission(t *testing.T) {
+	ms, fdb := newTestMissions(1, 2)
+	fdb.missions[1].Status = db.MissionStatusPending
+	fdb.missions[1].Duration = 3
+	fdb.missions[2].Status = db.MissionStatusPending
+	fdb.missions[2].StartTime = time.Now().Add(time.Hour)
+
+	ch, err := ms.JoinMission(1, "trainee")
+	if err != nil {
+		t.Fatalf("failed to join mission 1: %v", err)
+	}
+	v, _ := ms.m.Load(uint(1))
+	s := v.(*SingleMissionService)
+	t.Cleanup(s.stop)
+	m, _ := fdb.GetMission(1)
+	if m.Status != db.MissionStatusInProgress || m.StartTime.IsZero() || !ms.Pinned(1) {
+		t.Fatalf("mission 1 not started on first join: status %v, start time %v", m.Status, m.StartTime)
+	}
+
+	// 调度器按 Duration 提醒成员
+	s.lock.Lock()
+	s.status.MissionTime = 150
+	s.lock.Unlock()
+	if err = NewScheduler(fdb, ms).schedule(m, time.Now()); err != nil {
+		t.Fatalf("failed to schedule mission 1: %v", err)
+	}
+	timeout := time.After(time.Second)
+	for warned := false; !warned; {
+		select {
+		case msg := <-ch:
+			warned = msg.Action.Type == db.EventTypeMissionWarning
+		case <-timeout:
+			t.Fatal("no mission warning for mission started on join")
+		}
+	}
+
+	// 设置了开始时间的任务等待调度器开始
+	if _, err = ms.JoinMission(2, "trainee"); err != nil {
+		t.Fatalf("failed to join mission 2: %v", err)
+	}
+	v, _ = ms.m.Load(uint(2))
+	t.Cleanup(v.(*SingleMissionService).stop)
+	if m, _ := fdb.GetMission(2); m.Status != db.MissionStatusPending || ms.Pinned(2) {
+		t.Errorf("scheduled mission 2 started on join: status %v", m.Status)
+	}
+}
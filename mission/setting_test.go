@@ -0,0 +1,57 @@
+package mission
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/eli-yip/rocket-control/db"
+	"github.com/eli-yip/rocket-control/models"
+	"go.uber.org/zap"
+)
+
+func TestSettingEventFailures(t *testing.T) {
+	s, fdb, _ := newTestMission(t)
+	ch := make(chan models.WsMessage, eventBufferSize)
+	s.membersLock.Lock()
+	s.members["trainee"] = ch
+	s.membersLock.Unlock()
+
+	tests := []struct {
+		name     string
+		event    db.EventType
+		value    string
+		writeErr error
+		handle   func(models.Event, *zap.Logger) bool
+	}{
+		{"invalid setting", db.EventTypeThrust, "fast", nil, s.handleRocketSettingEvent},
+		{"setting not saved", db.EventTypeThrust, "80", errors.New("db down"), s.handleRocketSettingEvent},
+		{"invalid switch", db.EventTypeTriggerComms, "maybe", nil, s.handleRocketBoolSettingEvent},
+		{"switch not saved", db.EventTypeTriggerComms, "false", errors.New("db down"), s.handleRocketBoolSettingEvent},
+		{"invalid status", db.EventTypeFuelChange, "full", nil, s.handleRocketStatusEvent},
+		{"status not saved", db.EventTypeFuelChange, "5", errors.New("db down"), s.handleRocketStatusEvent},
+		{"invalid alarm", db.EventTypeAlarmAck, "x", nil, s.handleAlarmEvent},
+	}
+	for _, tt := range tests {
+		setting, status := *s.settings, *s.status
+		event := addTestEvent(t, fdb, tt.event, tt.value, "trainee")
+		fdb.writeErr = tt.writeErr
+		ok := tt.handle(event, zap.NewNop())
+		fdb.writeErr = nil
+		if ok {
+			t.Errorf("%s: handler succeeded", tt.name)
+			continue
+		}
+
+		if e := fdb.event(event.ID); e.Status != db.EventStatusFailed || e.Desc == "" {
+			t.Errorf("%s: event status %v, desc %q", tt.name, e.Status, e.Desc)
+		}
+		if msg := <-ch; msg.Status != db.EventStatusFailed || !strings.Contains(msg.Msg, "failed: ") {
+			t.Errorf("%s: broadcast %+v", tt.name, msg)
+		}
+		// 保存失败时内存中的状态不变
+		if *s.settings != setting || s.status.FuelLevel != status.FuelLevel {
+			t.Errorf("%s: state changed after failure", tt.name)
+		}
+	}
+}
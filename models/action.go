@@ -1,19 +1,30 @@
 package models
 
 import (
+	"time"
+
 	"github.com/eli-yip/rocket-control/db"
 )
 
 type Action struct {
 	Type  db.EventType `json:"type"`
 	Value string       `json:"value"`
+
+	// 客户端发送时的时间戳（毫秒）和客户端内单调递增的序号，用于在服务端重新排序
+	Timestamp int64  `json:"timestamp,omitempty"`
+	Seq       uint64 `json:"seq,omitempty"`
 }
 
 func (a *Action) ToEvent(user string) Event {
-	return Event{
+	e := Event{
 		EventType: a.Type,
 		Status:    db.EventStatusPending,
 		Value:     a.Value,
 		CreatedBy: user,
+		Seq:       a.Seq,
+	}
+	if a.Timestamp > 0 {
+		e.ClientTime = time.UnixMilli(a.Timestamp)
 	}
+	return e
 }
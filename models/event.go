@@ -2,6 +2,7 @@ package models
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/eli-yip/rocket-control/db"
 )
@@ -12,15 +13,50 @@ type Event struct {
 	Status    db.EventStatus
 	Value     string
 	CreatedBy string
+	Reason    string // 事件失败的原因
+
+	Setting *db.RocketSetting // 一次改变多项设置的事件（例如应用预设）携带的完整设置
+
+	Injected bool // 教员注入的事件，Client 只能看到它的效果
+
+	ClientTime time.Time // 客户端发送时间，为空表示未提供
+	Seq        uint64    // 客户端序号，为 0 表示未提供
 }
 
 func (e *Event) ToWsMessage(msg string) WsMessage {
+	if e.Reason != "" {
+		msg = fmt.Sprintf("event %d failed: %s", e.ID, e.Reason)
+	} else {
+		msg = fmt.Sprintf("event %d processed", e.ID)
+	}
 	return WsMessage{
 		Action: Action{
 			Type:  e.EventType,
 			Value: e.Value,
 		},
-		Status: e.Status,
-		Msg:    fmt.Sprintf("event %d processed", e.ID),
+		Status:    e.Status,
+		CreatedBy: e.CreatedBy,
+		Time:      time.Now(),
+		Msg:       msg,
+		Setting:   e.Setting,
+	}
+}
+
+// ReplayWsMessage 将历史事件转换为回放消息，保留事件原始的时间和状态
+func ReplayWsMessage(e *db.Event) WsMessage {
+	msg := fmt.Sprintf("event %d replayed", e.ID)
+	if e.PartOf != 0 {
+		msg = fmt.Sprintf("event %d (part of %d) replayed", e.ID, e.PartOf)
+	}
+	return WsMessage{
+		Action: Action{
+			Type:  e.Type,
+			Value: e.Value,
+		},
+		Status:    e.Status,
+		CreatedBy: e.CreatedBy,
+		Time:      e.CreatedAt,
+		Msg:       msg,
+		Replayed:  true,
 	}
 }
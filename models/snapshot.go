@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"github.com/eli-yip/rocket-control/db"
+)
+
+// Snapshot 是加入任务时发送的第一帧，包含任务的完整状态
+type Snapshot struct {
+	Mission  *db.Mission      `json:"mission"`
+	Setting  db.RocketSetting `json:"setting"`
+	Status   db.RocketStatus  `json:"status"`
+	Programs []RunningProgram `json:"programs"` // 正在运行的自定义程序等复合事件
+	Members  []string         `json:"members"`
+	Alarms   []*db.Alarm      `json:"alarms"` // 未清除的告警
+	SimTime  time.Time        `json:"sim_time"`
+	SimSpeed float64          `json:"sim_speed"`
+	Paused   bool             `json:"paused"`
+}
+
+// RunningProgram 描述一个正在运行的复合事件
+type RunningProgram struct {
+	EventID   uint         `json:"event_id"`
+	EventType db.EventType `json:"event_type"`
+	Name      string       `json:"name"`  // 程序名称
+	Value     string       `json:"value"` // 自定义程序为程序 ID
+	CreatedBy string       `json:"created_by"`
+	Step      int          `json:"step"`  // 正在执行的步骤序号，从 0 开始
+	Steps     int          `json:"steps"` // 步骤总数，程序加载前为 0
+}
+
+func (s *Snapshot) ToWsMessage() WsMessage {
+	return WsMessage{
+		Action:   Action{Type: db.EventTypeSnapshot},
+		Status:   db.EventStatusCompleted,
+		Time:     time.Now(),
+		Msg:      "snapshot",
+		Snapshot: s,
+	}
+}
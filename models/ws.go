@@ -7,9 +7,12 @@ import (
 )
 
 type WsMessage struct {
-	Action    Action         `json:"action"`
-	Status    db.EventStatus `json:"status"`
-	CreatedBy string         `json:"created_by"`
-	Time      time.Time      `json:"time"`
-	Msg       string         `json:"msg"`
+	Action    Action            `json:"action"`
+	Status    db.EventStatus    `json:"status"`
+	CreatedBy string            `json:"created_by"`
+	Time      time.Time         `json:"time"`
+	Msg       string            `json:"msg"`
+	Snapshot  *Snapshot         `json:"snapshot,omitempty"` // 只在 snapshot 消息中出现
+	Replayed  bool              `json:"replayed,omitempty"` // 加入任务时回放的历史事件
+	Setting   *db.RocketSetting `json:"setting,omitempty"`  // 应用预设后的完整设置
 }